@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for the Manager and MockService, so timing
+// can be driven by a FakeClock instead of the wall clock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+// RealClock is the wall clock.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time                         { return time.Now() }
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (RealClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+// FakeClock is a Clock that only moves when Advance is called.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewFakeClock returns a FakeClock reading now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *FakeClock) Sleep(d time.Duration) { <-c.After(d) }
+
+// Advance moves the clock forward by d and fires every After that has come
+// due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// Waiters returns how many After calls have not fired yet, so a test can
+// wait until the code under test is blocked on the clock before advancing.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// withTimeout is context.WithTimeout measured on c. With RealClock it is
+// exactly context.WithTimeout; with any other Clock the returned context is
+// cancelled, reporting context.DeadlineExceeded, once c says d has passed.
+//
+// A timeout never loosens ctx: if ctx's deadline is no later than d from
+// now, ctx is returned with only a cancel attached, and its deadline is the
+// one the callee sees.
+func withTimeout(ctx context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok && !c.Now().Add(d).Before(deadline) {
+		return context.WithCancel(ctx)
+	}
+	if _, ok := c.(RealClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	tc := &timeoutCtx{parent: ctx, deadline: c.Now().Add(d), done: make(chan struct{})}
+	// Armed before returning, so advancing c right away still fires it.
+	expired := c.After(d)
+	go func() {
+		select {
+		case <-expired:
+			tc.finish(context.DeadlineExceeded)
+		case <-ctx.Done():
+			tc.finish(ctx.Err())
+		case <-tc.done:
+		}
+	}()
+	return tc, func() { tc.finish(context.Canceled) }
+}
+
+// timeoutCtx is the context returned by withTimeout for clocks other than
+// RealClock. It has its own done channel rather than wrapping a cancelCtx:
+// contexts derived from it then take their error from Err, so they too
+// report context.DeadlineExceeded once the clock runs out.
+type timeoutCtx struct {
+	parent   context.Context
+	deadline time.Time
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// finish ends the context with err unless it has already ended.
+func (c *timeoutCtx) finish(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+func (c *timeoutCtx) Deadline() (time.Time, bool) {
+	if d, ok := c.parent.Deadline(); ok && d.Before(c.deadline) {
+		return d, true
+	}
+	return c.deadline, true
+}
+
+func (c *timeoutCtx) Done() <-chan struct{} { return c.done }
+
+func (c *timeoutCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *timeoutCtx) Value(key any) any { return c.parent.Value(key) }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFakeTimeoutReachesDerivedContexts(t *testing.T) {
+	fc := NewFakeClock(time.Unix(0, 0))
+	ctx, cancel := withTimeout(context.Background(), fc, time.Second)
+	defer cancel()
+	child, cancelChild := context.WithCancel(ctx)
+	defer cancelChild()
+
+	fc.Advance(time.Second)
+	<-child.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) || !errors.Is(child.Err(), context.DeadlineExceeded) {
+		t.Errorf("Err() = %v, derived Err() = %v, want both DeadlineExceeded", ctx.Err(), child.Err())
+	}
+}
+
+func TestFakeClockStopBudgetCountsAsTimeout(t *testing.T) {
+	fc := NewFakeClock(time.Unix(0, 0))
+	m, _ := newTestManager()
+	m.Clock = fc
+	m.Add(New("A", WithFakeDuration(0), WithClock(fc)))
+	m.Add(New("B", WithFakeDuration(0), WithStopDelay(DelayForever), WithStopTimeout(time.Hour), WithClock(fc)))
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+
+	ctx, cancel := withTimeout(context.Background(), fc, time.Second)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- m.StopAll(ctx) }()
+	var err error
+	for waiting := true; waiting; {
+		select {
+		case err = <-stopped:
+			waiting = false
+		case <-time.After(time.Millisecond):
+			fc.Advance(100 * time.Millisecond)
+		}
+	}
+
+	if !errors.Is(err, ErrStopTimeout) {
+		t.Errorf("StopAll error = %v, want ErrStopTimeout", err)
+	}
+	if got := m.StopTimeouts(); !slices.Equal(got, []string{"B"}) {
+		t.Errorf("StopTimeouts() = %v, want [B]", got)
+	}
+}
+
+func TestStartSeesEarlierParentDeadline(t *testing.T) {
+	for _, clock := range []Clock{RealClock{}, NewFakeClock(time.Now())} {
+		m, _ := newTestManager()
+		m.Clock = clock
+		ms := New("A", WithFakeDuration(0), WithStartTimeout(5*time.Second), WithClock(clock))
+		m.Add(ms)
+
+		ctx, cancel := withTimeout(context.Background(), clock, time.Second)
+		defer cancel()
+		parent, _ := ctx.Deadline()
+		if err := m.StartAll(ctx); err != nil {
+			t.Fatalf("%T: StartAll: %v", clock, err)
+		}
+		if start, _ := ms.Deadlines(); !start.Equal(parent) {
+			t.Errorf("%T: Start deadline = %v, want the parent's %v", clock, start, parent)
+		}
+	}
+}
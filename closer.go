@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// closerService is the Service returned by FromCloser.
+type closerService struct {
+	name string
+	c    io.Closer
+}
+
+// FromCloser adapts c, such as a database handle or a file, to a Service
+// named name. Start does nothing, since c is already open; Stop calls Close,
+// returning a ServiceError if it fails or outlives Stop's context.
+func FromCloser(name string, c io.Closer) Service {
+	return &closerService{name: name, c: c}
+}
+
+func (cs *closerService) Name() string { return cs.name }
+
+func (cs *closerService) Start(context.Context) error { return nil }
+
+func (cs *closerService) Stop(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() { done <- cs.c.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return &ServiceError{ServiceName: cs.name, Phase: PhaseStop, Err: err}
+		}
+		return nil
+	case <-ctx.Done():
+		return &ServiceError{ServiceName: cs.name, Phase: PhaseStop, Err: doneErr(ctx)}
+	}
+}
+
+// DefaultForceCloseTimeout bounds the Close made after a Stop timed out
+// unless Manager.ForceCloseTimeout says otherwise.
+const DefaultForceCloseTimeout = time.Second
+
+// forceClose escalates a Stop of s that failed with stopErr by timing out:
+// if s is also an io.Closer, Close is called as a hard kill and waited for
+// up to the force-close timeout. ctx has usually run out already, which is
+// why Stop timed out, so only its values are kept. The stop still counts as
+// failed, so stopErr is returned, joined with Close's error if it has one;
+// that error is passed to the OnError hooks like any other failed call.
+func (m *Manager) forceClose(ctx context.Context, s Service, stopErr error) error {
+	c, ok := s.(io.Closer)
+	if !ok {
+		return stopErr
+	}
+	m.Logger.Warnf("stop timed out for %s, forcing close", m.nameOf(s))
+	timeout := m.ForceCloseTimeout
+	if timeout <= 0 {
+		timeout = DefaultForceCloseTimeout
+	}
+	ctx, cancel := withTimeout(context.WithoutCancel(ctx), m.Clock, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- c.Close() }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = doneErr(ctx)
+	}
+	if err == nil {
+		return stopErr
+	}
+	closeErr := &ServiceError{ServiceName: m.nameOf(s), Phase: PhaseStop, Err: err, Detail: "forced close"}
+	m.runOnError(closeErr)
+	return errors.Join(stopErr, closeErr)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hungCloser never finishes Stop on its own, and takes a moment to Close.
+type hungCloser struct {
+	closed atomic.Bool
+}
+
+func (h *hungCloser) Name() string                { return "hung" }
+func (h *hungCloser) Start(context.Context) error { return nil }
+
+func (h *hungCloser) Stop(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (h *hungCloser) Close() error {
+	time.Sleep(50 * time.Millisecond)
+	h.closed.Store(true)
+	return nil
+}
+
+func TestForceCloseOutlivesExpiredBudget(t *testing.T) {
+	m, logger := newTestManager()
+	h := &hungCloser{}
+	m.Add(h)
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.StopAll(ctx); err == nil {
+		t.Error("StopAll returned nil, want the stop timeout")
+	}
+	if !logger.Contains("stop timed out for hung, forcing close") {
+		t.Errorf("force close not logged; got %q", logger.Lines())
+	}
+	if !h.closed.Load() {
+		t.Error("StopAll returned before Close finished")
+	}
+}
+
+// failingCloser is a hungCloser whose Close fails.
+type failingCloser struct{ hungCloser }
+
+func (f *failingCloser) Close() error { return errTestStop }
+
+func TestForceCloseFailureReachesOnError(t *testing.T) {
+	m, _ := newTestManager()
+	m.Add(&failingCloser{})
+	var mu sync.Mutex
+	var got []ServiceError
+	m.OnError(func(e ServiceError) {
+		mu.Lock()
+		got = append(got, e)
+		mu.Unlock()
+	})
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.StopAll(ctx); !errors.Is(err, errTestStop) {
+		t.Errorf("StopAll error = %v, want Close's failure", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var forced bool
+	for _, e := range got {
+		if e.Detail == "forced close" && errors.Is(e.Err, errTestStop) {
+			forced = true
+		}
+	}
+	if !forced {
+		t.Errorf("OnError got %v, want the forced close failure", got)
+	}
+}
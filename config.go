@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ConfigEnv names the environment variable holding the path of the service
+// configuration file.
+const ConfigEnv = "LIFECYCLE_CONFIG"
+
+// Config describes the services to run, in start order.
+type Config struct {
+	Services []ServiceConfig `json:"services"`
+}
+
+// ServiceConfig describes one MockService. Durations are strings in
+// time.ParseDuration format; empty ones keep the defaults.
+type ServiceConfig struct {
+	Name         string   `json:"name"`
+	FakeDuration Duration `json:"fake_duration"`
+	StartTimeout Duration `json:"start_timeout"`
+	StopTimeout  Duration `json:"stop_timeout"`
+	// Critical marks the service Critical; see Manager.HealthInterval.
+	Critical bool `json:"critical"`
+	// Config is handed to the service's Start through the context; see
+	// ServiceConfigFromContext.
+	Config map[string]any `json:"config"`
+}
+
+// Duration is a time.Duration read from a JSON string such as "1.5s".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ReadConfig reads and validates the JSON configuration at path.
+func ReadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool, len(cfg.Services))
+	for i, sc := range cfg.Services {
+		if sc.Name == "" {
+			return Config{}, fmt.Errorf("%s: service %d has no name", path, i)
+		}
+		if seen[sc.Name] {
+			return Config{}, fmt.Errorf("%s: duplicate service name %s", path, sc.Name)
+		}
+		seen[sc.Name] = true
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads the JSON configuration at path and returns the services
+// it describes, in declared order.
+func LoadConfig(path string) ([]Service, error) {
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	services := make([]Service, 0, len(cfg.Services))
+	for _, sc := range cfg.Services {
+		services = append(services, sc.Service())
+	}
+	return services, nil
+}
+
+// Service returns the MockService sc describes.
+func (sc ServiceConfig) Service() Service {
+	var opts []Option
+	if sc.FakeDuration != 0 {
+		opts = append(opts, WithFakeDuration(time.Duration(sc.FakeDuration)))
+	}
+	opts = append(opts,
+		WithStartTimeout(time.Duration(sc.StartTimeout)),
+		WithStopTimeout(time.Duration(sc.StopTimeout)),
+	)
+	if sc.Critical {
+		opts = append(opts, WithCritical())
+	}
+	return New(sc.Name, opts...)
+}
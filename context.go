@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type runIDKey struct{}
+
+// WithRunID returns a copy of ctx carrying the run ID id.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunIDFromContext returns the run ID carried by ctx, or "" if there is none.
+func RunIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+type serviceConfigKey struct{}
+
+// WithServiceConfig returns a copy of ctx carrying cfg, the configuration of
+// the service the context is handed to.
+func WithServiceConfig(ctx context.Context, cfg map[string]any) context.Context {
+	return context.WithValue(ctx, serviceConfigKey{}, cfg)
+}
+
+// ServiceConfigFromContext returns the service configuration carried by ctx,
+// or nil if there is none. The Manager attaches what was set with
+// SetServiceConfig to the context of every Start.
+func ServiceConfigFromContext(ctx context.Context) map[string]any {
+	cfg, _ := ctx.Value(serviceConfigKey{}).(map[string]any)
+	return cfg
+}
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying l.
+func ContextWithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFromContext returns the logger carried by ctx, or the one
+// NewStdLogger returns if there is none. The Manager attaches its Logger to every context it hands to
+// services, so their messages end up in the same stream as its own.
+func LoggerFromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return l
+	}
+	return NewStdLogger()
+}
+
+// newRunID returns a random version 4 UUID.
+func newRunID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// sleep waits for d on c or until ctx is done, whichever comes first, and
+// returns ctx's error in the latter case.
+func sleep(ctx context.Context, c Clock, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.After(d):
+		return nil
+	}
+}
+
+// doneErr says why ctx is done: errTimeLimit if its deadline passed, or
+// ctx.Err() if it was cancelled.
+func doneErr(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errTimeLimit
+	}
+	return ctx.Err()
+}
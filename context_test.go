@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestServicesLogThroughManagerLogger(t *testing.T) {
+	m, logger := newTestManager()
+	m.Add(New("A", WithFakeDuration(0)))
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+	m.StopAll(context.Background())
+
+	lines := logger.Lines()
+	var sawService, sawManager bool
+	for _, line := range lines {
+		if strings.HasPrefix(line, "[INFO] starting service A (run "+m.runID+")") {
+			sawService = true
+		}
+		if strings.HasPrefix(line, "[INFO] shutdown complete") {
+			sawManager = sawService
+		}
+	}
+	if !sawService || !sawManager {
+		t.Errorf("service and Manager messages not in one stream; got %q", lines)
+	}
+}
+
+// runLogger logs from its Run through the logger in ctx.
+type runLogger struct{ *NullService }
+
+func (r runLogger) Run(ctx context.Context) error {
+	LoggerFromContext(ctx).Infof("running")
+	<-ctx.Done()
+	return nil
+}
+
+func TestSupervisedServicesLogThroughManagerLogger(t *testing.T) {
+	m, logger := newTestManager()
+	m.Add(runLogger{NewNull("R", &CallRecorder{})})
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+	m.Supervise(context.Background())
+	if !waitFor(func() bool { return logger.Contains("[INFO] running") }) {
+		t.Errorf("Run's message didn't reach the Manager's logger; got %q", logger.Lines())
+	}
+	m.Shutdown()
+	m.StopAll(context.Background())
+}
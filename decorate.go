@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware wraps a Service in another that adds behavior around it.
+type Middleware func(Service) Service
+
+// Chain applies mw to s in order, so the last one ends up outermost:
+// Chain(s, WithRecover, Logging(l)) is WithLogging(WithRecover(s), l), and a
+// panic in s is recovered first and then logged as an error.
+func Chain(s Service, mw ...func(Service) Service) Service {
+	for _, wrap := range mw {
+		s = wrap(s)
+	}
+	return s
+}
+
+// Timeout is WithTimeout as a Middleware.
+func Timeout(start, stop time.Duration) Middleware {
+	return func(s Service) Service { return WithTimeout(s, start, stop) }
+}
+
+// Logging is WithLogging as a Middleware.
+func Logging(logger Logger) Middleware {
+	return func(s Service) Service { return WithLogging(s, logger) }
+}
+
+// timeoutService is the Service returned by WithTimeout.
+type timeoutService struct {
+	inner       Service
+	start, stop time.Duration
+}
+
+// WithTimeout wraps s so that Start and Stop each run under their own
+// deadline, start and stop respectively; zero or less means no extra limit.
+// The inner call gets the shortened context, but the wrapper returns once the
+// deadline passes even if the inner call ignores it, so third-party services
+// that never look at ctx still can't hold up the manager. If the inner call
+// finishes first, its error is returned unchanged.
+//
+// The wrapper forwards Name; other optional interfaces of s are hidden.
+func WithTimeout(s Service, start, stop time.Duration) Service {
+	return &timeoutService{inner: s, start: start, stop: stop}
+}
+
+func (t *timeoutService) Name() string { return serviceName(t.inner) }
+
+func (t *timeoutService) Start(ctx context.Context) error {
+	return t.call(ctx, PhaseStart, t.start, t.inner.Start)
+}
+
+func (t *timeoutService) Stop(ctx context.Context) error {
+	return t.call(ctx, PhaseStop, t.stop, t.inner.Stop)
+}
+
+func (t *timeoutService) call(ctx context.Context, phase string, limit time.Duration, fn func(context.Context) error) error {
+	if limit <= 0 {
+		return fn(ctx)
+	}
+	began := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	// Buffered so the goroutine can finish after we stop waiting for it.
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return &ServiceError{ServiceName: t.Name(), Phase: phase, Err: doneErr(ctx), Detail: budgetDetail(ctx, began, time.Now())}
+	}
+}
+
+// serviceName returns s's Name if it has one, or its type otherwise. It is
+// the registry-free counterpart to Manager.nameOf, for wrappers.
+func serviceName(s Service) string {
+	if n, ok := s.(Named); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// loggingService is the Service returned by WithLogging.
+type loggingService struct {
+	inner  Service
+	logger Logger
+}
+
+// WithLogging wraps s so that every Start and Stop is logged to logger with
+// its duration and, on failure, its error. It forwards Name, so it stacks
+// with WithTimeout in either order.
+func WithLogging(s Service, logger Logger) Service {
+	return &loggingService{inner: s, logger: logger}
+}
+
+func (l *loggingService) Name() string { return serviceName(l.inner) }
+
+func (l *loggingService) Start(ctx context.Context) error {
+	return l.call(ctx, "start", "starting", "started", l.inner.Start)
+}
+
+func (l *loggingService) Stop(ctx context.Context) error {
+	return l.call(ctx, "stop", "stopping", "stopped", l.inner.Stop)
+}
+
+func (l *loggingService) call(ctx context.Context, verb, doing, done string, fn func(context.Context) error) error {
+	name := l.Name()
+	l.logger.Infof("%s service %s", doing, name)
+	began := time.Now()
+	err := fn(ctx)
+	elapsed := time.Since(began).Round(time.Millisecond)
+	if err != nil {
+		l.logger.Errorf("service %s failed to %s after %v: %v", name, verb, elapsed, err)
+		return err
+	}
+	l.logger.Infof("service %s %s in %v", name, done, elapsed)
+	return nil
+}
+
+// recoverService is the Service returned by WithRecover.
+type recoverService struct {
+	inner Service
+}
+
+// WithRecover wraps s so that a panic in Start or Stop is returned as a
+// *ServiceError wrapping a *PanicError with the recovered value and stack,
+// for callers that drive services without a Manager. Services run by a
+// Manager are already guarded the same way.
+func WithRecover(s Service) Service {
+	return &recoverService{inner: s}
+}
+
+func (r *recoverService) Name() string { return serviceName(r.inner) }
+
+func (r *recoverService) Start(ctx context.Context) error {
+	return r.call(ctx, PhaseStart, r.inner.Start)
+}
+
+func (r *recoverService) Stop(ctx context.Context) error {
+	return r.call(ctx, PhaseStop, r.inner.Stop)
+}
+
+func (r *recoverService) call(ctx context.Context, phase string, fn func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &ServiceError{ServiceName: r.Name(), Phase: phase, Err: &PanicError{Value: v, Stack: debug.Stack()}}
+		}
+	}()
+	return fn(ctx)
+}
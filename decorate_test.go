@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithRecoverTurnsPanicIntoError(t *testing.T) {
+	s := WithRecover(New("A", WithPanicOnStart(), WithLogger(&testLogger{})))
+	err := s.Start(context.Background())
+
+	var pe *PanicError
+	if !errors.As(err, &pe) || len(pe.Stack) == 0 {
+		t.Fatalf("Start error = %v, want a PanicError with a stack", err)
+	}
+	var se *ServiceError
+	if !errors.As(err, &se) || se.ServiceName != "A" || se.Phase != PhaseStart {
+		t.Errorf("Start error = %v, want a start ServiceError for A", err)
+	}
+
+	m, _ := newTestManager()
+	m.Add(WithRecover(New("A", WithPanicOnStart(), WithLogger(&testLogger{}))))
+	_, err = m.Run(context.Background())
+	// A failed start exits with ExitStartFailure rather than crashing.
+	if code := ExitCodeOf(err); code != ExitStartFailure {
+		t.Errorf("ExitCodeOf(%v) = %d, want %d", err, code, ExitStartFailure)
+	}
+}
+
+func TestChainRecoversThenLogs(t *testing.T) {
+	logger := &testLogger{}
+	raw := New("A", WithPanicOnStart(), WithLogger(&testLogger{}))
+	s := Chain(raw, WithRecover, Logging(logger), Timeout(time.Second, time.Second))
+
+	err := s.Start(context.Background())
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Start error = %v, want a recovered panic", err)
+	}
+	if !logger.Contains("[ERROR] service A failed to start") {
+		t.Errorf("logging middleware didn't see the recovered panic; got %q", logger.Lines())
+	}
+	if name := serviceName(s); name != "A" {
+		t.Errorf("chained service is called %s, want A", name)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// AddWithDeps registers s and records that it must start after every service
+// named in dependsOn. Dependencies are resolved by name when starting, so
+// they may be added in any order. Like Add it fails on a duplicate name.
+func (m *Manager) AddWithDeps(s Service, dependsOn ...string) error {
+	if err := m.Add(s); err != nil {
+		return err
+	}
+	if len(dependsOn) == 0 {
+		return nil
+	}
+	name := m.nameOf(s)
+	m.regMu.Lock()
+	defer m.regMu.Unlock()
+	if m.deps == nil {
+		m.deps = make(map[string][]string)
+	}
+	m.deps[name] = append(m.deps[name], dependsOn...)
+	return nil
+}
+
+// startPlan returns the stages to start, in order. Without dependencies
+// these are the registered stages. With dependencies every service becomes
+// its own stage, sorted so it comes after what it depends on; services with
+// no ordering constraint between them go by Priority, then registration
+// order. Without dependencies, stages are sorted by Priority.
+// Unknown dependencies, cycles and dependencies combined with multi-service
+// stages are reported before anything is started.
+func (m *Manager) startPlan() ([][]Service, error) {
+	m.regMu.RLock()
+	services := slices.Clone(m.services)
+	stages := slices.Clone(m.stages)
+	deps := maps.Clone(m.deps)
+	m.regMu.RUnlock()
+
+	if len(deps) == 0 {
+		return sortByPriority(stages), nil
+	}
+	for _, stage := range stages {
+		if len(stage) > 1 {
+			return nil, errors.New("dependencies can't be combined with multi-service stages")
+		}
+	}
+
+	byName := make(map[string]int, len(services))
+	for i, s := range services {
+		byName[m.nameOf(s)] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make([]int, len(services))
+	plan := make([][]Service, 0, len(services))
+	var path []string
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		name := m.nameOf(services[i])
+		switch marks[i] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, name):]), name)
+			return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		marks[i] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			j, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("service %s depends on unknown service %s", name, dep)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[i] = visited
+		plan = append(plan, []Service{services[i]})
+		return nil
+	}
+
+	// Visiting in priority order makes priority the tie-breaker between
+	// services that dependencies leave unordered.
+	order := make([]int, len(services))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return priorityOf(services[a]) - priorityOf(services[b])
+	})
+	for _, i := range order {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestRollbackFollowsDependencyOrder(t *testing.T) {
+	m, _ := newTestManager()
+	rec := &CallRecorder{}
+	// Registered leaf first, so reverse registration order would differ
+	// from reverse topological order.
+	m.AddWithDeps(failingStart{NewNull("api", rec)}, "cache")
+	m.AddWithDeps(NewNull("cache", rec), "db")
+	m.AddWithDeps(NewNull("db", rec))
+
+	if err := m.StartAll(context.Background()); err == nil {
+		t.Fatal("StartAll returned nil, want api's failure")
+	}
+	want := []string{"start db", "start cache", "start api", "stop cache", "stop db"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultDrainTimeout bounds each Drain call unless Manager.DrainTimeout says
+// otherwise.
+const DefaultDrainTimeout = 5 * time.Second
+
+// Drainable is implemented by services that need to finish in-flight work
+// before they can stop. Drain should stop taking new work and return once
+// the current work is done or ctx ends.
+type Drainable interface {
+	Drain(ctx context.Context) error
+}
+
+// ShutdownStrategy decides when services are drained relative to being
+// stopped.
+type ShutdownStrategy int
+
+const (
+	// StrategyInterleaved drains each service right before stopping it. It
+	// is the default.
+	StrategyInterleaved ShutdownStrategy = iota
+	// StrategyDrainFirst drains every service, in reverse start order,
+	// before stopping any, for services that still need each other while
+	// they drain.
+	StrategyDrainFirst
+)
+
+// drainedKey marks stop contexts whose services have already been drained.
+type drainedKey struct{}
+
+// drainAll drains services one after another in reverse order, each until
+// its Drain returns or times out. The returned context tells stop not to
+// drain them again.
+func (m *Manager) drainAll(ctx context.Context, services []Service) context.Context {
+	for i := len(services) - 1; i >= 0; i-- {
+		m.drain(ctx, services[i])
+	}
+	return context.WithValue(ctx, drainedKey{}, true)
+}
+
+// drain calls Drain on s if it implements Drainable, bounded by the drain
+// timeout. A failure is logged only: the service is stopped regardless.
+func (m *Manager) drain(ctx context.Context, s Service) {
+	d, ok := s.(Drainable)
+	if !ok {
+		return
+	}
+	timeout := m.DrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	drainCtx, cancel := withTimeout(ctx, m.Clock, timeout)
+	defer cancel()
+
+	m.Logger.Infof("draining service %s", m.nameOf(s))
+	if err := m.guard(s, PhaseDrain, func() error { return d.Drain(drainCtx) }); err != nil {
+		m.Logger.Warnf("%v, stopping anyway", err)
+	}
+}
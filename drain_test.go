@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestShutdownStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy ShutdownStrategy
+		want     []string
+	}{
+		{
+			name:     "interleaved",
+			strategy: StrategyInterleaved,
+			want:     []string{"stop C", "drain B", "stop B", "drain A", "stop A"},
+		},
+		{
+			name:     "drain first",
+			strategy: StrategyDrainFirst,
+			want:     []string{"drain B", "drain A", "stop C", "stop B", "stop A"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			m.ShutdownStrategy = tt.strategy
+			rec := &CallRecorder{}
+			m.Add(NewDrainableNull("A", rec))
+			m.Add(NewDrainableNull("B", rec))
+			m.Add(NewNull("C", rec))
+			if err := m.StartAll(context.Background()); err != nil {
+				t.Fatalf("StartAll: %v", err)
+			}
+			if err := m.StopAll(context.Background()); err != nil {
+				t.Fatalf("StopAll: %v", err)
+			}
+			if got := rec.Calls()[3:]; !slices.Equal(got, tt.want) {
+				t.Errorf("shutdown calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import "context"
+
+// DryRun logs the order in which StartAll would start services and StopAll
+// would stop them, with the timeout each call would get, without calling
+// anything. It uses the same plan as StartAll, so it reports the same
+// errors, and the same order and stop timeouts as StopAll, so ShutdownOrder
+// and StopTimeoutPolicy are honored.
+func (m *Manager) DryRun(ctx context.Context) error {
+	plan, err := m.startPlan()
+	if err != nil {
+		return err
+	}
+	for _, stage := range plan {
+		for _, s := range stage {
+			m.Logger.Infof("would start %s (timeout %v)", m.nameOf(s), startTimeoutFor(s))
+		}
+	}
+	stages := m.shutdownStages(plan)
+	for i := len(stages) - 1; i >= 0; i-- {
+		for _, s := range stages[i] {
+			m.Logger.Infof("would stop %s (timeout %v)", m.nameOf(s), m.stopTimeout(s))
+		}
+	}
+	return ctx.Err()
+}
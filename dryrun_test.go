@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRunFollowsShutdownSettings(t *testing.T) {
+	m, logger := newTestManager()
+	m.ShutdownOrder = OrderForward
+	m.StopTimeoutPolicy = StopTimeoutPolicy{StartFactor: 2, Min: 5 * time.Second}
+	m.Add(New("A", WithFakeDuration(0)))
+	m.Add(New("B", WithFakeDuration(0)))
+	// A recorded start lets StartFactor apply.
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+	m.StopAll(context.Background())
+
+	logger.lines = nil
+	if err := m.DryRun(context.Background()); err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+	var stops []string
+	for _, line := range logger.Lines() {
+		if strings.Contains(line, "would stop") {
+			stops = append(stops, line)
+		}
+	}
+	want := []string{"[INFO] would stop A (timeout 5s)", "[INFO] would stop B (timeout 5s)"}
+	if !slices.Equal(stops, want) {
+		t.Errorf("stop lines = %q, want %q", stops, want)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Lifecycle phases reported in ServiceError.Phase.
+const (
+	PhaseStart  = "start"
+	PhaseStop   = "stop"
+	PhaseHealth = "health"
+	PhaseReload = "reload"
+	PhaseDrain  = "drain"
+	PhaseWarmup = "warmup"
+)
+
+var errTimeLimit = errors.New("time limit exceeded")
+
+// ErrStartTimeout and ErrStopTimeout match, with errors.Is, a ServiceError
+// from a Start (or Warmup) or a Stop that ran out of time, as opposed to one
+// the service returned of its own accord.
+var (
+	ErrStartTimeout = errors.New("start timed out")
+	ErrStopTimeout  = errors.New("stop timed out")
+)
+
+// ServiceError reports which service failed and in which phase.
+type ServiceError struct {
+	ServiceName string
+	Phase       string
+	Err         error
+	// Detail, if set, is appended to the message in parentheses.
+	Detail string
+}
+
+func (e *ServiceError) Error() string {
+	verb := e.Phase + "ing"
+	switch e.Phase {
+	case PhaseStart:
+		verb = "starting"
+	case PhaseStop:
+		verb = "stopping"
+	case PhaseHealth:
+		verb = "health-checking"
+	case PhaseReload:
+		verb = "reloading"
+	case PhaseDrain:
+		verb = "draining"
+	case PhaseWarmup:
+		verb = "warming up"
+	}
+	msg := fmt.Sprintf("%v while %s service %s", e.Err, verb, e.ServiceName)
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg
+}
+
+func (e *ServiceError) Unwrap() error { return e.Err }
+
+// Is reports whether e is a timeout matching ErrStartTimeout or
+// ErrStopTimeout.
+func (e *ServiceError) Is(target error) bool {
+	switch target {
+	case ErrStartTimeout:
+		return (e.Phase == PhaseStart || e.Phase == PhaseWarmup) && isTimeout(e.Err)
+	case ErrStopTimeout:
+		return e.Phase == PhaseStop && isTimeout(e.Err)
+	}
+	return false
+}
+
+// PanicError carries a value recovered from a panicking Start or Stop along
+// with the stack at the point of the panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
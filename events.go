@@ -0,0 +1,56 @@
+package main
+
+import "time"
+
+// eventBuffer is how many events Events holds for a slow consumer before
+// new ones are dropped.
+const eventBuffer = 64
+
+// Event is a lifecycle transition of one service.
+type Event struct {
+	ServiceName string
+	Phase       string
+	State       State
+	Err         error
+	Time        time.Time
+}
+
+// Events returns the channel lifecycle events are delivered on: when a
+// service begins starting, is running, begins stopping, and has stopped or
+// failed. Delivery never blocks the Manager; if the consumer falls more than
+// a buffer behind, events are dropped. The channel is closed once StopAll
+// has stopped the last service, so consumers can range over it. Once a Run
+// has returned, Events returns a new channel, the one the next Run delivers
+// on.
+func (m *Manager) Events() <-chan Event {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.lastRunMu.Lock()
+	ended := m.lastRunEnded
+	m.lastRunMu.Unlock()
+	if m.eventsClosed && ended {
+		m.events, m.eventsClosed = make(chan Event, eventBuffer), false
+	}
+	return m.events
+}
+
+func (m *Manager) emit(s Service, phase string, state State, err error) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	if m.eventsClosed {
+		return
+	}
+	select {
+	case m.events <- Event{ServiceName: m.nameOf(s), Phase: phase, State: state, Err: err, Time: m.Clock.Now()}:
+	default:
+	}
+}
+
+func (m *Manager) closeEvents() {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	if !m.eventsClosed {
+		m.eventsClosed = true
+		close(m.events)
+	}
+}
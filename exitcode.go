@@ -0,0 +1,66 @@
+package main
+
+import "errors"
+
+// ExitCode is a process exit status that tells apart how a run failed.
+type ExitCode int
+
+const (
+	// ExitClean means every phase succeeded.
+	ExitClean ExitCode = 0
+	// ExitFailure is used for failures outside starting and stopping, such
+	// as a supervised service crashing or a critical one turning unhealthy.
+	ExitFailure ExitCode = 1
+	// ExitStartFailure means services failed to come up, including failed
+	// warmups and health checks.
+	ExitStartFailure ExitCode = 2
+	// ExitStopFailure means everything came up but something failed to
+	// come down.
+	ExitStopFailure ExitCode = 3
+)
+
+// ExitCodeOf maps an error returned by Manager.Run to an ExitCode. Phases are
+// read from the outermost ServiceError on every branch of err, including
+// joined ones; a startup failure outranks a shutdown failure.
+func ExitCodeOf(err error) ExitCode {
+	if err == nil {
+		return ExitClean
+	}
+	code := ExitFailure
+	walkErrors(err, func(e error) bool {
+		if _, ok := e.(*UnhealthyError); ok {
+			// A health check failing after startup is a runtime failure.
+			return false
+		}
+		se, ok := e.(*ServiceError)
+		if !ok {
+			return true
+		}
+		switch se.Phase {
+		case PhaseStart, PhaseWarmup, PhaseHealth:
+			code = ExitStartFailure
+		case PhaseStop:
+			if code != ExitStartFailure {
+				code = ExitStopFailure
+			}
+		}
+		return false
+	})
+	return code
+}
+
+// walkErrors calls fn for err and everything it wraps, depth first. It
+// doesn't descend below an error for which fn returns false.
+func walkErrors(err error, fn func(error) bool) {
+	if err == nil || !fn(err) {
+		return
+	}
+	switch u := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, e := range u.Unwrap() {
+			walkErrors(e, fn)
+		}
+	default:
+		walkErrors(errors.Unwrap(err), fn)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/wanna-beat-by-bit/dns_assignment_startstop
+
+go 1.22
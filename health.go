@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WaitForQuorum blocks until at least n registered services are running,
+// re-checking every PollInterval, and returns the running ones in
+// registration order. A service counts as running if it reports
+// StateRunning, or, if it isn't Stateful, if it has started and not stopped
+// since. If ctx ends first, the error names the services not yet running.
+func (m *Manager) WaitForQuorum(ctx context.Context, n int) ([]Service, error) {
+	interval := m.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	for {
+		var up []Service
+		var waiting []string
+		for _, s := range m.registered() {
+			if m.isUp(s) {
+				up = append(up, s)
+			} else {
+				waiting = append(waiting, m.nameOf(s))
+			}
+		}
+		if len(up) >= n {
+			return up, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("quorum of %d not reached, %d/%d running, not running: %s: %w",
+				n, len(up), len(up)+len(waiting), strings.Join(waiting, ", "), ctx.Err())
+		case <-m.Clock.After(interval):
+		}
+	}
+}
+
+// Critical is implemented by services the program can't do without. When
+// HealthInterval is set, a critical service failing its health check during
+// the working phase shuts the whole program down.
+type Critical interface {
+	Critical() bool
+}
+
+func isCritical(s Service) bool {
+	c, ok := s.(Critical)
+	return ok && c.Critical()
+}
+
+// UnhealthyError is the RuntimeErr recorded when a critical service fails a
+// health check during the working phase.
+type UnhealthyError struct {
+	ServiceName string
+	Err         error
+}
+
+func (e *UnhealthyError) Error() string {
+	return "critical service " + e.ServiceName + " unhealthy: " + e.Err.Error()
+}
+
+func (e *UnhealthyError) Unwrap() error { return e.Err }
+
+// DefaultPollInterval is how often WaitForRunning re-checks health unless
+// Manager.PollInterval says otherwise.
+const DefaultPollInterval = 100 * time.Millisecond
+
+// WaitForRunning polls HealthCheck on every started service that implements
+// HealthChecker until all of them pass or ctx is done. On timeout the last
+// health failure is returned along with ctx's error.
+func (m *Manager) WaitForRunning(ctx context.Context) error {
+	interval := m.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	for {
+		err := m.checkHealth(ctx, m.Started())
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), err)
+		case <-m.Clock.After(interval):
+		}
+	}
+}
+
+// watchHealth polls HealthCheck on every started service that implements
+// HealthChecker once per HealthInterval until shutdown begins. A failing
+// critical service is reported by RuntimeErr and begins shutdown with
+// ReasonUnhealthy; other services are only logged when they turn unhealthy
+// and when they recover.
+func (m *Manager) watchHealth(ctx context.Context) {
+	ctx, cancel := context.WithCancel(m.withRun(ctx))
+	defer cancel()
+	done := m.Done()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	unhealthy := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.Clock.After(m.HealthInterval):
+		}
+		for _, s := range m.Started() {
+			if _, ok := s.(HealthChecker); !ok {
+				continue
+			}
+			err := m.checkHealth(ctx, []Service{s})
+			if ctx.Err() != nil {
+				return
+			}
+			name := m.nameOf(s)
+			switch {
+			case err != nil && isCritical(s):
+				m.Logger.Errorf("critical service %s unhealthy: %v, shutting down", name, err)
+				m.failFor(ReasonUnhealthy, &UnhealthyError{ServiceName: name, Err: err})
+				return
+			case err != nil && !unhealthy[name]:
+				m.Logger.Warnf("service %s unhealthy: %v", name, err)
+				unhealthy[name] = true
+			case err == nil && unhealthy[name]:
+				m.Logger.Infof("service %s healthy again", name)
+				delete(unhealthy, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// testLogger records every message instead of writing it, so tests stay
+// quiet and can assert on what was logged.
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Debugf(format string, args ...any) { l.record("DEBUG", format, args) }
+func (l *testLogger) Infof(format string, args ...any)  { l.record("INFO", format, args) }
+func (l *testLogger) Warnf(format string, args ...any)  { l.record("WARN", format, args) }
+func (l *testLogger) Errorf(format string, args ...any) { l.record("ERROR", format, args) }
+
+func (l *testLogger) record(level, format string, args []any) {
+	l.mu.Lock()
+	l.lines = append(l.lines, "["+level+"] "+fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+}
+
+// Lines returns the messages logged so far, each with its level prefix.
+func (l *testLogger) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.lines)
+}
+
+// Contains reports whether any message logged so far contains s.
+func (l *testLogger) Contains(s string) bool {
+	for _, line := range l.Lines() {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestManager returns a Manager that logs to a testLogger.
+func newTestManager() (*Manager, *testLogger) {
+	m := NewManager()
+	logger := &testLogger{}
+	m.Logger = logger
+	return m, logger
+}
+
+// settleGoroutines waits up to a second for the goroutine count to drop back
+// to at most want and returns the last count seen.
+func settleGoroutines(want int) int {
+	n := runtime.NumGoroutine()
+	for deadline := time.Now().Add(time.Second); n > want && time.Now().Before(deadline); n = runtime.NumGoroutine() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return n
+}
+
+// waitFor polls cond for up to a second and reports whether it became true.
+func waitFor(cond func() bool) bool {
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package main
+
+import "errors"
+
+// OnBeforeStart registers fn to run before any service is started. Hooks run
+// in registration order; the first error aborts startup.
+func (m *Manager) OnBeforeStart(fn func() error) {
+	m.beforeStart = append(m.beforeStart, fn)
+}
+
+// OnAfterStop registers fn to run after StopAll has stopped the last
+// service, whether or not shutdown succeeded. Hooks run in registration
+// order.
+func (m *Manager) OnAfterStop(fn func()) {
+	m.afterStop = append(m.afterStop, fn)
+}
+
+// OnError registers fn to be called with every failed service call: each
+// Start attempt, Warmup, HealthCheck, Drain, Stop and forced Close. It is
+// called synchronously, before the failure is logged or acted on, and may be
+// called from several goroutines at once when a stage starts or stops
+// concurrently. It has no say in what the Manager does next.
+func (m *Manager) OnError(fn func(ServiceError)) {
+	m.onError = append(m.onError, fn)
+}
+
+func (m *Manager) runBeforeStart() error {
+	for _, fn := range m.beforeStart {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) runAfterStop() {
+	for _, fn := range m.afterStop {
+		fn()
+	}
+}
+
+func (m *Manager) runOnError(err error) {
+	var se *ServiceError
+	if !errors.As(err, &se) {
+		return
+	}
+	for _, fn := range m.onError {
+		fn(*se)
+	}
+}
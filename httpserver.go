@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// HTTPServerService runs an *http.Server as a Service.
+type HTTPServerService struct {
+	name      string
+	newServer func() *http.Server
+	mu        sync.Mutex
+	current   *http.Server
+}
+
+// NewHTTPServerService returns a Service named name that serves on the
+// Addr of the server newServer returns. An http.Server can't serve again
+// once it has been shut down, so every Start calls newServer for a new one.
+func NewHTTPServerService(name string, newServer func() *http.Server) *HTTPServerService {
+	return &HTTPServerService{name: name, newServer: newServer}
+}
+
+func (h *HTTPServerService) Name() string { return h.name }
+
+// Start returns once the server is listening, or with the error that kept
+// it from listening. Serving continues in the background; errors other than
+// http.ErrServerClosed are logged to the context's logger.
+func (h *HTTPServerService) Start(ctx context.Context) error {
+	srv := h.newServer()
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", srv.Addr)
+	if err != nil {
+		return &ServiceError{ServiceName: h.name, Phase: PhaseStart, Err: err}
+	}
+	h.mu.Lock()
+	h.current = srv
+	h.mu.Unlock()
+	logger := LoggerFromContext(ctx)
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("%s server: %v", h.name, err)
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the server down gracefully, waiting for active requests until
+// ctx is done.
+func (h *HTTPServerService) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	srv := h.current
+	h.current = nil
+	h.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		return &ServiceError{ServiceName: h.name, Phase: PhaseStop, Err: err}
+	}
+	return nil
+}
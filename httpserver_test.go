@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPServerServiceRestarts(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(http.ResponseWriter, *http.Request) {})
+	h := NewHTTPServerService("http", func() *http.Server {
+		return &http.Server{Addr: addr, Handler: mux}
+	})
+	ctx := ContextWithLogger(context.Background(), &testLogger{})
+
+	for round := 1; round <= 2; round++ {
+		if err := h.Start(ctx); err != nil {
+			t.Fatalf("start %d: %v", round, err)
+		}
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			t.Fatalf("round %d: %v", round, err)
+		}
+		resp.Body.Close()
+		if err := h.Stop(ctx); err != nil {
+			t.Fatalf("stop %d: %v", round, err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "context"
+
+// Labeled is implemented by services that carry labels such as
+// "tier": "web" for selecting them in bulk.
+type Labeled interface {
+	Labels() map[string]string
+}
+
+// HasLabel returns a predicate for StopWhere matching services labeled
+// key=value.
+func HasLabel(key, value string) func(Service) bool {
+	return func(s Service) bool {
+		l, ok := s.(Labeled)
+		if !ok {
+			return false
+		}
+		v, ok := l.Labels()[key]
+		return ok && v == value
+	}
+}
+
+// StopWhere stops the started services matching pred, in reverse start
+// order, and leaves the rest running. Stopped services are dropped from
+// Started, so a later StopAll won't touch them again. Stop errors are
+// returned joined.
+func (m *Manager) StopWhere(ctx context.Context, pred func(Service) bool) error {
+	m.startedMu.Lock()
+	var matched, rest [][]Service
+	for _, stage := range m.started {
+		var in, out []Service
+		for _, s := range stage {
+			if pred(s) {
+				in = append(in, s)
+			} else {
+				out = append(out, s)
+			}
+		}
+		if len(in) > 0 {
+			matched = append(matched, in)
+		}
+		if len(out) > 0 {
+			rest = append(rest, out)
+		}
+	}
+	m.started = rest
+	m.startedMu.Unlock()
+
+	ctx = m.withRun(ctx)
+	return m.stopReverse(ctx, matched)
+}
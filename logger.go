@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+// Logger is the sink for lifecycle messages.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// LogLevelEnv names the environment variable NewStdLogger reads its level
+// from: debug, info, warn or error.
+const LogLevelEnv = "LIFECYCLE_LOG_LEVEL"
+
+// Level is the minimum severity a StdLogger writes.
+type Level int
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// ParseLevel returns the Level named s, case-insensitively.
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, true
+	case "info":
+		return LevelInfo, true
+	case "warn":
+		return LevelWarn, true
+	case "error":
+		return LevelError, true
+	}
+	return LevelInfo, false
+}
+
+// StdLogger writes through the standard log package with the bracketed
+// level prefixes the program has always used. Messages below Level are
+// dropped; the zero value writes everything but debug messages.
+type StdLogger struct {
+	Level Level
+}
+
+// NewStdLogger returns a StdLogger at the level named by LogLevelEnv, or
+// LevelInfo if it is unset or unknown.
+func NewStdLogger() StdLogger {
+	env := os.Getenv(LogLevelEnv)
+	if env == "" {
+		return StdLogger{}
+	}
+	level, ok := ParseLevel(env)
+	if !ok {
+		log.Printf("[WARN] unknown %s %q, using info", LogLevelEnv, env)
+	}
+	return StdLogger{Level: level}
+}
+
+func (l StdLogger) Debugf(format string, args ...any) { l.logf(LevelDebug, "[DEBUG] ", format, args) }
+func (l StdLogger) Infof(format string, args ...any)  { l.logf(LevelInfo, "[INFO] ", format, args) }
+func (l StdLogger) Warnf(format string, args ...any)  { l.logf(LevelWarn, "[WARN] ", format, args) }
+func (l StdLogger) Errorf(format string, args ...any) { l.logf(LevelError, "[ERROR] ", format, args) }
+
+func (l StdLogger) logf(level Level, prefix, format string, args []any) {
+	if level < l.Level {
+		return
+	}
+	log.Printf(prefix+format, args...)
+}
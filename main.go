@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+func main() {
+	mgr := NewManager()
+	mgr.ResultPath = os.Getenv(ResultEnv)
+
+	services := []Service{
+		New("A", WithFakeDuration(1*time.Second)),
+		New("B", WithFakeDuration(2*time.Second)),
+		New("C", WithFakeDuration(1*time.Second)),
+	}
+	if path := os.Getenv(ConfigEnv); path != "" {
+		cfg, err := ReadConfig(path)
+		if err != nil {
+			mgr.Logger.Errorf("%v", err)
+			os.Exit(int(ExitFailure))
+		}
+		services = nil
+		for _, sc := range cfg.Services {
+			services = append(services, sc.Service())
+			if sc.Config != nil {
+				mgr.SetServiceConfig(sc.Name, sc.Config)
+			}
+		}
+	}
+	for _, s := range services {
+		if err := mgr.Add(s); err != nil {
+			mgr.Logger.Errorf("%v", err)
+			os.Exit(int(ExitFailure))
+		}
+	}
+
+	_, err := mgr.Run(context.Background())
+	os.Exit(int(ExitCodeOf(err)))
+}
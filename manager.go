@@ -0,0 +1,974 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budgets used for services that don't declare their own via Timeouts.
+const (
+	DefaultStartTimeout = 3 * time.Second
+	DefaultStopTimeout  = 2 * time.Second
+)
+
+// DefaultShutdownTimeout bounds the whole stop phase of Run.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// ShutdownOrder is the direction in which StopAll walks the started
+// services.
+type ShutdownOrder int
+
+const (
+	// OrderReverse stops the last started service first. It is the default
+	// and what the lifecycle guarantees unless told otherwise.
+	OrderReverse ShutdownOrder = iota
+	// OrderForward stops services in the order they were started, which
+	// suits stateless front ends that should stop taking traffic first.
+	OrderForward
+)
+
+// RetryPolicy controls how often a failing Start is retried. MaxAttempts
+// below 2 means a single attempt.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// Manager starts services in registration order and stops them in reverse.
+type Manager struct {
+	// Retry is applied to every Start call.
+	Retry RetryPolicy
+	// Logger receives every message the Manager emits.
+	Logger Logger
+	// Clock measures every timeout, backoff and duration. NewManager sets
+	// RealClock.
+	Clock Clock
+	// ShutdownTimeout bounds the stop phase of Run, across all services.
+	ShutdownTimeout time.Duration
+	// StartStagger, if positive, is waited between consecutive stages in
+	// StartAll to spread the load of starting services. Cancelling the
+	// context cuts the wait short and aborts the startup.
+	StartStagger time.Duration
+	// StartupTimeout, if positive, bounds the start phase of Run, across all
+	// services. Each Start still gets no more than its own start timeout.
+	StartupTimeout time.Duration
+	// ShutdownGracePeriod, if positive, is how long Run waits after a
+	// signal before it begins stopping services, so that load balancers
+	// can stop sending traffic first.
+	ShutdownGracePeriod time.Duration
+	// StopTimeoutPolicy decides each Stop's timeout. The zero value uses
+	// fixed timeouts.
+	StopTimeoutPolicy StopTimeoutPolicy
+	// ShutdownOrder sets the direction of StopAll. Unwinding a failed
+	// startup is always done in reverse.
+	ShutdownOrder ShutdownOrder
+	// RollbackTimeout, if positive, bounds all the stops made to unwind a
+	// failed startup, separately from the normal shutdown budget.
+	RollbackTimeout time.Duration
+	// ShutdownStrategy decides whether StopAll and StopAllParallel drain
+	// each service just before stopping it or all of them up front.
+	ShutdownStrategy ShutdownStrategy
+	// DrainTimeout bounds each Drain call during shutdown. Zero means
+	// DefaultDrainTimeout.
+	DrainTimeout time.Duration
+	// ForceCloseTimeout bounds the Close of an io.Closer service whose Stop
+	// timed out. Zero means DefaultForceCloseTimeout.
+	ForceCloseTimeout time.Duration
+	// MaxUptime, if positive, makes Run shut down by itself after the
+	// services have been up this long. Zero waits for a signal forever.
+	MaxUptime time.Duration
+	// HealthInterval, if positive, makes Run re-check health this often
+	// during the working phase. A Critical service failing its check shuts
+	// the program down; see watchHealth.
+	HealthInterval time.Duration
+	// PollInterval is how often WaitForRunning re-checks health. Zero means
+	// DefaultPollInterval.
+	PollInterval time.Duration
+	// ProgressInterval is how often a Start still in progress is logged.
+	// NewManager sets DefaultProgressInterval; zero turns it off.
+	ProgressInterval time.Duration
+	// ResultPath, if set, is where Run writes its Result as JSON before
+	// returning. See WriteResult.
+	ResultPath string
+	// ExitWhenEmpty makes Run return straight away when no services are
+	// registered, instead of waiting for a signal.
+	ExitWhenEmpty bool
+	// IgnoreStopErrors makes stop failures best-effort: they are logged as
+	// warnings and left out of Run's error, so a run that started cleanly
+	// still exits with ExitClean. StopAll still returns them.
+	IgnoreStopErrors bool
+	// MaxRestarts is how many times Supervise restarts a crashed service.
+	MaxRestarts int
+	// Slog, if set, additionally receives one structured record per Start
+	// and Stop call.
+	Slog *slog.Logger
+
+	// regMu guards the registration: services, stages, byName, the IDs,
+	// deps and configs.
+	regMu    sync.RWMutex
+	services []Service
+	stages   [][]Service
+	byName   map[string]Service
+	// Every registration gets an ID. Named services are keyed by name, so
+	// services of types that can't be map keys can still be added as long
+	// as they have a Name.
+	namedIDs   map[string]int
+	unnamedIDs map[Service]int
+	nextID     int
+	deps       map[string][]string
+	configs    map[string]map[string]any
+
+	beforeStart []func() error
+	afterStop   []func()
+	onError     []func(ServiceError)
+
+	runIDMu sync.Mutex
+	runID   string
+	// changeMu serializes runtime changes (Reload, AddAndStart and
+	// RemoveAndStop) with each other and with the start of StopAll, which
+	// sets stopping.
+	changeMu sync.Mutex
+	stopping bool
+
+	startedMu sync.Mutex
+	started   [][]Service
+	skipped   []Service
+
+	runtimeMu  sync.Mutex
+	runtimeErr error
+
+	lastRunMu    sync.Mutex
+	lastRunEnded bool
+	lastRunErr   error
+
+	stopTimeoutsMu sync.Mutex
+	stopTimeouts   []string
+
+	metricsMu sync.Mutex
+	metrics   Metrics
+	outcomes  map[string]*ServiceResult
+
+	events       chan Event
+	eventsMu     sync.Mutex
+	eventsClosed bool
+
+	signals []os.Signal
+	sigCh   chan os.Signal
+
+	// shutdownMu guards done, which is closed once shutdown begins, and why
+	// it began. Run replaces them when the Manager is run again.
+	shutdownMu sync.Mutex
+	done       chan struct{}
+	reason     ShutdownReason
+	signal     os.Signal
+
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// NewManager returns an empty Manager.
+func NewManager() *Manager {
+	return &Manager{
+		Logger:           NewStdLogger(),
+		Clock:            RealClock{},
+		ShutdownTimeout:  DefaultShutdownTimeout,
+		ProgressInterval: DefaultProgressInterval,
+		events:           make(chan Event, eventBuffer),
+		done:             make(chan struct{}),
+	}
+}
+
+// Shutdown asks the program to leave its working phase. It is safe to call
+// more than once and from several goroutines.
+func (m *Manager) Shutdown() {
+	m.shutdown(ReasonRequested)
+}
+
+// RuntimeErr returns the first failure during the working phase that made
+// the Manager shut itself down, or nil.
+func (m *Manager) RuntimeErr() error {
+	m.runtimeMu.Lock()
+	defer m.runtimeMu.Unlock()
+	return m.runtimeErr
+}
+
+// fail records err as the RuntimeErr, unless one is already recorded, and
+// begins shutdown.
+func (m *Manager) fail(err error) {
+	m.failFor(ReasonFailure, err)
+}
+
+// failFor is fail with r as the shutdown reason.
+func (m *Manager) failFor(r ShutdownReason, err error) {
+	m.runtimeMu.Lock()
+	if m.runtimeErr == nil {
+		m.runtimeErr = err
+	}
+	m.runtimeMu.Unlock()
+	m.shutdown(r)
+}
+
+// Done is closed once Shutdown has been called. Every Run after the first
+// replaces it with a new channel.
+func (m *Manager) Done() <-chan struct{} {
+	m.shutdownMu.Lock()
+	defer m.shutdownMu.Unlock()
+	return m.done
+}
+
+// Add registers s. Services are started in the order they were added,
+// unless a service implements Prioritized.
+func (m *Manager) Add(s Service) error {
+	return m.AddStage(s)
+}
+
+// AddStage registers services as one stage. Services within a stage start
+// and stop concurrently; stages start in the order they were added and stop
+// in reverse. Add is AddStage with a single service. Both refuse, and
+// register nothing, if a service's Name is already taken.
+func (m *Manager) AddStage(services ...Service) error {
+	if len(services) == 0 {
+		return nil
+	}
+	m.regMu.Lock()
+	defer m.regMu.Unlock()
+	if err := m.indexLocked(services); err != nil {
+		return err
+	}
+	m.services = append(m.services, services...)
+	m.stages = append(m.stages, services)
+	return nil
+}
+
+// indexLocked gives each of services an ID and adds the named ones to
+// byName. Names identify services to Get, Reload, dependencies and the logs,
+// so if one is already taken nothing is added and an error naming both
+// registrations is returned. Services without a Name are called service#ID;
+// the ID is assigned once, here, so the name doesn't change when other
+// services are removed. A service is told apart from the others by
+// comparing it with them, so values that can't be compared, such as structs
+// with func fields, are refused: register a pointer to them instead. m.regMu
+// must be held for writing.
+func (m *Manager) indexLocked(services []Service) error {
+	for _, s := range services {
+		if !reflect.ValueOf(s).Comparable() {
+			return fmt.Errorf("service %T is not comparable; register a pointer to it", s)
+		}
+	}
+	if m.byName == nil {
+		m.byName = make(map[string]Service)
+		m.namedIDs = make(map[string]int)
+		m.unnamedIDs = make(map[Service]int)
+	}
+	seen := make(map[string]int, len(services))
+	for i, s := range services {
+		n, ok := s.(Named)
+		if !ok {
+			continue
+		}
+		name, id := n.Name(), m.nextID+i
+		if _, dup := m.byName[name]; dup {
+			return fmt.Errorf("duplicate service name %s: service#%d and service#%d", name, m.namedIDs[name], id)
+		}
+		if prev, dup := seen[name]; dup {
+			return fmt.Errorf("duplicate service name %s: service#%d and service#%d", name, prev, id)
+		}
+		seen[name] = id
+	}
+	for _, s := range services {
+		if n, ok := s.(Named); ok {
+			m.byName[n.Name()] = s
+			m.namedIDs[n.Name()] = m.nextID
+		} else {
+			m.unnamedIDs[s] = m.nextID
+		}
+		m.nextID++
+	}
+	return nil
+}
+
+// SetServiceConfig records cfg as the configuration of the service named
+// name. It is attached to the context of every Start of that service.
+func (m *Manager) SetServiceConfig(name string, cfg map[string]any) {
+	m.regMu.Lock()
+	defer m.regMu.Unlock()
+	if m.configs == nil {
+		m.configs = make(map[string]map[string]any)
+	}
+	m.configs[name] = cfg
+}
+
+// withServiceConfig attaches s's configuration, if any, to ctx.
+func (m *Manager) withServiceConfig(ctx context.Context, s Service) context.Context {
+	name := m.nameOf(s)
+	m.regMu.RLock()
+	cfg, ok := m.configs[name]
+	m.regMu.RUnlock()
+	if !ok {
+		return ctx
+	}
+	return WithServiceConfig(ctx, cfg)
+}
+
+// Get returns the registered service named name. Only services implementing
+// Named can be looked up.
+func (m *Manager) Get(name string) (Service, bool) {
+	m.regMu.RLock()
+	defer m.regMu.RUnlock()
+	s, ok := m.byName[name]
+	return s, ok
+}
+
+// registered returns a snapshot of the registered services in registration
+// order.
+func (m *Manager) registered() []Service {
+	m.regMu.RLock()
+	defer m.regMu.RUnlock()
+	return slices.Clone(m.services)
+}
+
+// StartAll starts every stage in registration order, moved as needed so that
+// dependencies declared with AddWithDeps come first. On the first failure
+// the stages that already started are stopped in reverse and the start error
+// is returned. Once all are up, services implementing HealthChecker are
+// checked; a failing check is handled like a failed start.
+//
+// Every Start gets a child of ctx, so cancelling ctx aborts the service
+// being started, no further services are started, and the ones already up
+// are stopped again. Likewise a deadline on ctx is a budget for the whole
+// startup: each Start gets the lesser of what is left of it and its own
+// start timeout.
+func (m *Manager) StartAll(ctx context.Context) error {
+	plan, err := m.startPlan()
+	if err != nil {
+		return err
+	}
+	m.setStarted(nil)
+	if len(plan) == 0 {
+		m.Logger.Infof("no services to start")
+		return nil
+	}
+	if err := m.runBeforeStart(); err != nil {
+		return err
+	}
+	ctx = m.newRun(ctx)
+	for i, stage := range plan {
+		if i > 0 && m.StartStagger > 0 {
+			names := make([]string, len(stage))
+			for j, s := range stage {
+				names[j] = m.nameOf(s)
+			}
+			m.Logger.Infof("waiting %v before starting %s", m.StartStagger, strings.Join(names, ", "))
+			sleep(ctx, m.Clock, m.StartStagger)
+		}
+		select {
+		case <-ctx.Done():
+			m.rollback(ctx)
+			return fmt.Errorf("startup aborted: %w", ctx.Err())
+		default:
+		}
+		up, err := m.startStage(ctx, stage, 0)
+		m.addStarted(up)
+		if err != nil {
+			m.rollback(ctx)
+			return budgetErr(ctx, err)
+		}
+	}
+	if err := m.checkHealth(ctx, m.Started()); err != nil {
+		m.rollback(ctx)
+		return budgetErr(ctx, err)
+	}
+	return nil
+}
+
+// budgetErr marks err as caused by the overall startup budget if ctx's
+// deadline has passed, as opposed to a single service's start timeout.
+func budgetErr(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("startup budget exceeded: %w", err)
+	}
+	return err
+}
+
+// StartAllParallel starts every service concurrently, with at most
+// maxConcurrency starts in flight (zero or less means no limit), and waits
+// for all of them. The first failure stops further starts from being
+// admitted and cancels the context shared by the ones in flight; once those
+// have returned, the services that did start are stopped in reverse
+// registration order and all start errors are returned joined. Stages and
+// dependencies are not taken into account.
+func (m *Manager) StartAllParallel(ctx context.Context, maxConcurrency int) error {
+	m.setStarted(nil)
+	services := m.registered()
+	if len(services) == 0 {
+		m.Logger.Infof("no services to start")
+		return nil
+	}
+	if err := m.runBeforeStart(); err != nil {
+		return err
+	}
+	ctx = m.newRun(ctx)
+
+	up, err := m.startStage(ctx, services, maxConcurrency)
+	for _, s := range up {
+		m.addStarted([]Service{s})
+	}
+	if err != nil {
+		m.rollback(ctx)
+		return err
+	}
+	if err := m.checkHealth(ctx, up); err != nil {
+		m.rollback(ctx)
+		return err
+	}
+	return nil
+}
+
+// withRun attaches the Manager's Logger and the current run ID, if any, to
+// ctx.
+func (m *Manager) withRun(ctx context.Context) context.Context {
+	ctx = ContextWithLogger(ctx, m.Logger)
+	id := m.currentRunID()
+	if id == "" {
+		return ctx
+	}
+	return WithRunID(ctx, id)
+}
+
+// currentRunID returns the ID of the current run, or "" if nothing has been
+// started yet.
+func (m *Manager) currentRunID() string {
+	m.runIDMu.Lock()
+	defer m.runIDMu.Unlock()
+	return m.runID
+}
+
+// rollback stops what the current startup brought up, in the reverse of the
+// order it came up in; with dependencies that is reverse topological order,
+// whatever ShutdownOrder says. A service whose Start failed was never up and
+// isn't stopped. It ignores ctx's cancellation so a cancelled startup still
+// unwinds cleanly, and bounds the whole unwinding by RollbackTimeout if set.
+// Stop sees ReasonStartFailed, or the shutdown reason if shutdown is what
+// aborted the startup.
+func (m *Manager) rollback(ctx context.Context) {
+	ctx, cancel := m.rollbackCtx(ctx)
+	defer cancel()
+	m.stopReverse(ctx, m.startedStages())
+}
+
+// rollbackCtx derives the context for stops that unwind a startup from ctx.
+func (m *Manager) rollbackCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = context.WithValue(context.WithoutCancel(ctx), rollbackKey{}, true)
+	reason := ReasonStartFailed
+	select {
+	case <-m.Done():
+		reason = m.shutdownReason()
+	default:
+	}
+	ctx = m.withReason(ctx, reason)
+	if m.RollbackTimeout > 0 {
+		return withTimeout(ctx, m.Clock, m.RollbackTimeout)
+	}
+	return ctx, func() {}
+}
+
+// rollbackKey marks contexts used to unwind a failed startup.
+type rollbackKey struct{}
+
+// newRun generates the run ID shared by this startup and the following
+// shutdown and attaches it, with the Logger, to ctx.
+func (m *Manager) newRun(ctx context.Context) context.Context {
+	m.resetOutcomes()
+	m.runIDMu.Lock()
+	m.runID = newRunID()
+	m.runIDMu.Unlock()
+	return m.withRun(ctx)
+}
+
+// startStage starts the services of one stage concurrently, at most limit at
+// a time if limit is positive. The first failure cancels the starts in
+// flight and stops further ones from being admitted. It returns the services
+// that came up, in stage order, and every start error joined.
+func (m *Manager) startStage(ctx context.Context, stage []Service, limit int) ([]Service, error) {
+	if len(stage) == 1 {
+		up, err := m.startOrSkip(ctx, stage[0])
+		if !up {
+			return nil, err
+		}
+		return stage, nil
+	}
+
+	sharedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if limit <= 0 {
+		limit = len(stage)
+	}
+	sem := make(chan struct{}, limit)
+	ok := make([]bool, len(stage))
+	errs := make([]error, len(stage))
+
+	var wg sync.WaitGroup
+	for i, s := range stage {
+		select {
+		case sem <- struct{}{}:
+		case <-sharedCtx.Done():
+		}
+		if sharedCtx.Err() != nil {
+			if ctx.Err() != nil {
+				errs[i] = fmt.Errorf("startup aborted: %w", ctx.Err())
+			}
+			break
+		}
+		wg.Add(1)
+		go func(i int, s Service) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			up, err := m.startOrSkip(sharedCtx, s)
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			ok[i] = up
+		}(i, s)
+	}
+	wg.Wait()
+
+	var up []Service
+	for i, s := range stage {
+		if ok[i] {
+			up = append(up, s)
+		}
+	}
+	return up, errors.Join(errs...)
+}
+
+// Started returns the services the last StartAll or StartAllParallel brought
+// up, in start order. After a failed start it still lists the services that
+// came up before the failure, even though they have been stopped again.
+func (m *Manager) Started() []Service {
+	return slices.Concat(m.startedStages()...)
+}
+
+func (m *Manager) startedStages() [][]Service {
+	m.startedMu.Lock()
+	defer m.startedMu.Unlock()
+	return slices.Clone(m.started)
+}
+
+func (m *Manager) setStarted(stages [][]Service) {
+	m.startedMu.Lock()
+	m.started = stages
+	m.skipped = nil
+	m.startedMu.Unlock()
+}
+
+func (m *Manager) addStarted(stage []Service) {
+	if len(stage) == 0 {
+		return
+	}
+	m.startedMu.Lock()
+	m.started = append(m.started, stage)
+	m.startedMu.Unlock()
+}
+
+// start calls s.Start under its own timeout, retrying according to m.Retry.
+// A cancelled ctx ends the retries immediately.
+func (m *Manager) start(ctx context.Context, s Service) error {
+	attempts := max(m.Retry.MaxAttempts, 1)
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			m.Logger.Warnf("retrying start of service %s (attempt %d/%d)", m.nameOf(s), attempt, attempts)
+		}
+		startCtx, cancel := withTimeout(m.withServiceConfig(ctx, s), m.Clock, startTimeoutFor(s))
+		m.emit(s, PhaseStart, StateStarting, nil)
+		began := m.Clock.Now()
+		doneProgress := m.reportProgress(s, began)
+		err = m.guard(s, PhaseStart, func() error { return s.Start(startCtx) })
+		if err == nil {
+			err = m.awaitReady(startCtx, s)
+		}
+		doneProgress()
+		m.observe(startCtx, s, PhaseStart, m.Clock.Now().Sub(began), err)
+		cancel()
+		if err == nil {
+			return m.warmup(ctx, s)
+		}
+		if attempt == attempts {
+			return err
+		}
+		if sleep(ctx, m.Clock, m.Retry.Backoff) != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// checkHealth runs HealthCheck on every service that implements it, each
+// under its start timeout, and returns the first failure.
+func (m *Manager) checkHealth(ctx context.Context, services []Service) error {
+	for _, s := range services {
+		hc, ok := s.(HealthChecker)
+		if !ok {
+			continue
+		}
+		checkCtx, cancel := withTimeout(ctx, m.Clock, startTimeoutFor(s))
+		err := m.guard(s, PhaseHealth, func() error { return hc.HealthCheck(checkCtx) })
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopAll stops the stages reported by Started in reverse, or in start order
+// under OrderForward. A failing stop is logged and the rest are still
+// stopped; every stop error is returned joined, in the order the services
+// were stopped.
+//
+// ctx is the budget for the whole shutdown: each Stop gets a child context
+// whose deadline is the earlier of ctx's and the service's own stop timeout.
+// Services reached after ctx has expired are still stopped, with an expired
+// context, so they can fail fast.
+//
+// The stop loop runs at most once per Manager; later calls wait for it and
+// return the same error.
+func (m *Manager) StopAll(ctx context.Context) error {
+	return m.stopOnceWith(ctx, func(ctx context.Context) error {
+		return m.stopReverse(ctx, m.shutdownStages(m.startedStages()))
+	})
+}
+
+// shutdownStages returns stages, in start order, arranged so that
+// stopReverse walks them in the direction ShutdownOrder asks for.
+func (m *Manager) shutdownStages(stages [][]Service) [][]Service {
+	stages = slices.Clone(stages)
+	if m.ShutdownOrder == OrderForward {
+		slices.Reverse(stages)
+	}
+	return stages
+}
+
+// StopAllParallel stops the started services with at most maxConcurrency
+// Stop calls in flight; zero or less means no limit. Services are launched
+// in reverse start order and each one waits for a free slot, so a service
+// never begins stopping before every service started after it has begun
+// stopping. Completion order within the window is not guaranteed. Like
+// StopAll it runs at most once and returns every stop error joined.
+func (m *Manager) StopAllParallel(ctx context.Context, maxConcurrency int) error {
+	return m.stopOnceWith(ctx, func(ctx context.Context) error {
+		services := m.Started()
+		if maxConcurrency <= 0 {
+			maxConcurrency = max(len(services), 1)
+		}
+		sem := make(chan struct{}, maxConcurrency)
+		errs := make([]error, len(services))
+
+		var wg sync.WaitGroup
+		for i := len(services) - 1; i >= 0; i-- {
+			s := services[i]
+			if !m.shouldStop(s) {
+				continue
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(i int, s Service) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				errs[i] = m.stop(ctx, s)
+			}(i, s)
+		}
+		wg.Wait()
+
+		var failed []error
+		for i := len(errs) - 1; i >= 0; i-- {
+			if errs[i] != nil {
+				m.logStopErr(errs[i])
+				failed = append(failed, errs[i])
+			}
+		}
+		return errors.Join(failed...)
+	})
+}
+
+// stopOnceWith runs stopAll under the once guard shared by StopAll and
+// StopAllParallel, then closes the events channel and runs the after-stop
+// hooks. Unless ctx already carries a ShutdownReason, the one Shutdown was
+// called with is attached. Under StrategyDrainFirst every service is drained
+// before stopAll runs.
+func (m *Manager) stopOnceWith(ctx context.Context, stopAll func(ctx context.Context) error) error {
+	m.stopOnce.Do(func() {
+		defer m.closeEvents()
+		defer m.runAfterStop()
+		m.changeMu.Lock()
+		m.stopping = true
+		m.changeMu.Unlock()
+		if _, ok := ReasonFromContext(ctx); !ok {
+			ctx = m.withReason(ctx, m.shutdownReason())
+		}
+		stoppable := m.stoppable()
+		began := m.Clock.Now()
+		ctx = m.withRun(ctx)
+		if m.ShutdownStrategy == StrategyDrainFirst {
+			ctx = m.drainAll(ctx, stoppable)
+		}
+		m.stopErr = stopAll(ctx)
+		if late := m.StopTimeouts(); len(late) > 0 {
+			m.Logger.Warnf("%d service(s) exceeded their stop deadline: %s", len(late), strings.Join(late, ", "))
+		}
+		m.logShutdownSummary(len(stoppable), m.stopErr, m.Clock.Now().Sub(began))
+	})
+	return m.stopErr
+}
+
+// shouldStop reports whether s is up and so needs stopping. The Manager
+// never calls Stop on a service that isn't, so implementations needn't guard
+// against it themselves.
+func (m *Manager) shouldStop(s Service) bool {
+	if m.isUp(s) {
+		return true
+	}
+	m.Logger.Debugf("skipping stop of non-running service %s", m.nameOf(s))
+	return false
+}
+
+// stoppable returns the started services a shutdown would call Stop on.
+func (m *Manager) stoppable() []Service {
+	var services []Service
+	for _, s := range m.Started() {
+		if !m.isUp(s) {
+			continue
+		}
+		services = append(services, s)
+	}
+	return services
+}
+
+// logShutdownSummary logs the one line that says how a shutdown of total
+// services went, e.g. "shutdown complete: 2/3 services stopped cleanly,
+// 1 failed (C), total 4.1s". Its format is meant to be grepped for.
+func (m *Manager) logShutdownSummary(total int, stopErr error, took time.Duration) {
+	var failed []string
+	walkErrors(stopErr, func(e error) bool {
+		if se, ok := e.(*ServiceError); ok {
+			if !slices.Contains(failed, se.ServiceName) {
+				failed = append(failed, se.ServiceName)
+			}
+			return false
+		}
+		return true
+	})
+	if len(failed) == 0 {
+		m.Logger.Infof("shutdown complete: all %d services stopped cleanly, total %.1fs", total, took.Seconds())
+		return
+	}
+	m.Logger.Infof("shutdown complete: %d/%d services stopped cleanly, %d failed (%s), total %.1fs",
+		total-len(failed), total, len(failed), strings.Join(failed, ", "), took.Seconds())
+}
+
+// stop calls s.Stop under its own timeout, bounded by ctx. Outside of a
+// rollback, a Drainable service is drained first unless ctx says it already
+// has been. A service that is also an io.Closer is closed if Stop times out.
+func (m *Manager) stop(ctx context.Context, s Service) error {
+	if ctx.Value(rollbackKey{}) != nil {
+		m.Logger.Infof("rolling back service %s", m.nameOf(s))
+	} else if ctx.Value(drainedKey{}) == nil {
+		m.drain(ctx, s)
+	}
+	stopCtx, cancel := withTimeout(ctx, m.Clock, m.stopTimeout(s))
+	defer cancel()
+	m.emit(s, PhaseStop, StateStopping, nil)
+	began := m.Clock.Now()
+	err := m.guard(s, PhaseStop, func() error { return s.Stop(stopCtx) })
+	m.observe(stopCtx, s, PhaseStop, m.Clock.Now().Sub(began), err)
+	if isTimeout(err) {
+		m.stopTimeoutsMu.Lock()
+		m.stopTimeouts = append(m.stopTimeouts, m.nameOf(s))
+		m.stopTimeoutsMu.Unlock()
+		err = m.forceClose(ctx, s, err)
+	}
+	return err
+}
+
+// StopTimeouts returns the names of the services whose Stop ran past its
+// deadline, in the order they were stopped.
+func (m *Manager) StopTimeouts() []string {
+	m.stopTimeoutsMu.Lock()
+	defer m.stopTimeoutsMu.Unlock()
+	return slices.Clone(m.stopTimeouts)
+}
+
+// stopReverse stops stages last to first, the services within a stage
+// concurrently. Failures are logged and don't stop the unwinding.
+//
+// If ctx has a deadline, the time left is shared out fairly: each service
+// gets an equal slice of what remains, recomputed after every stage, so a
+// fast stop hands its unused time to the services after it.
+func (m *Manager) stopReverse(ctx context.Context, stages [][]Service) error {
+	left := 0
+	for _, stage := range stages {
+		left += len(stage)
+	}
+
+	var errs []error
+	for i := len(stages) - 1; i >= 0; i-- {
+		stage := stages[i]
+		stageCtx, cancel := ctx, context.CancelFunc(func() {})
+		if deadline, ok := ctx.Deadline(); ok {
+			budget := splitBudget(deadline.Sub(m.Clock.Now()), left) * time.Duration(len(stage))
+			stageCtx, cancel = withTimeout(ctx, m.Clock, budget)
+		}
+		errs = append(errs, m.stopStage(stageCtx, stage)...)
+		cancel()
+		left -= len(stage)
+	}
+	return errors.Join(errs...)
+}
+
+// logStopErr logs a failed stop, as a warning under IgnoreStopErrors.
+func (m *Manager) logStopErr(err error) {
+	if m.IgnoreStopErrors {
+		m.Logger.Warnf("%v", err)
+		return
+	}
+	m.Logger.Errorf("%v", err)
+}
+
+// splitBudget returns each service's fair share of remaining when
+// servicesLeft services still have to stop. No time left yields zero, which
+// makes for an already expired context.
+func splitBudget(remaining time.Duration, servicesLeft int) time.Duration {
+	if remaining <= 0 {
+		return 0
+	}
+	if servicesLeft <= 1 {
+		return remaining
+	}
+	return remaining / time.Duration(servicesLeft)
+}
+
+// stopStage stops the services of one stage concurrently and returns their
+// errors in reverse stage order.
+func (m *Manager) stopStage(ctx context.Context, stage []Service) []error {
+	errs := make([]error, len(stage))
+	var wg sync.WaitGroup
+	for i, s := range stage {
+		if !m.shouldStop(s) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, s Service) {
+			defer wg.Done()
+			errs[i] = m.stop(ctx, s)
+		}(i, s)
+	}
+	wg.Wait()
+
+	var failed []error
+	for i := len(stage) - 1; i >= 0; i-- {
+		if errs[i] != nil {
+			m.logStopErr(errs[i])
+			failed = append(failed, errs[i])
+		}
+	}
+	return failed
+}
+
+func startTimeoutFor(s Service) time.Duration {
+	if t, ok := s.(Timeouts); ok && t.StartTimeout() > 0 {
+		return t.StartTimeout()
+	}
+	return DefaultStartTimeout
+}
+
+func stopTimeoutFor(s Service) time.Duration {
+	if t, ok := s.(Timeouts); ok && t.StopTimeout() > 0 {
+		return t.StopTimeout()
+	}
+	return DefaultStopTimeout
+}
+
+// guard runs fn on behalf of s. A panic is turned into a ServiceError, and
+// so is any returned error that isn't one already, so callers always learn
+// which service failed and in which phase. Every failure is then passed to
+// the OnError hooks.
+func (m *Manager) guard(s Service, phase string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		var se *ServiceError
+		if err != nil && !errors.As(err, &se) {
+			err = &ServiceError{ServiceName: m.nameOf(s), Phase: phase, Err: err}
+		}
+		if err != nil {
+			m.runOnError(err)
+		}
+	}()
+	return fn()
+}
+
+// nameOf returns s's Name if it implements Named, or service#ID with the ID
+// it was given when registered otherwise.
+func (m *Manager) nameOf(s Service) string {
+	if n, ok := s.(Named); ok {
+		return n.Name()
+	}
+	m.regMu.RLock()
+	id, ok := m.unnamedIDs[s]
+	m.regMu.RUnlock()
+	if ok {
+		return fmt.Sprintf("service#%d", id)
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// observe records the outcome of a finished Start or Stop call.
+func (m *Manager) observe(ctx context.Context, s Service, phase string, d time.Duration, err error) {
+	m.recordDuration(s, phase, d)
+	m.recordOutcome(s, phase, d, err)
+	m.logEvent(ctx, s, phase, d, err)
+
+	state := StateRunning
+	if phase == PhaseStop {
+		state = StateStopped
+	}
+	if err != nil {
+		state = StateFailed
+	}
+	m.emit(s, phase, state, err)
+}
+
+// logEvent emits a structured record for a finished Start or Stop call.
+func (m *Manager) logEvent(ctx context.Context, s Service, phase string, d time.Duration, err error) {
+	if m.Slog == nil {
+		return
+	}
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.String("service", m.nameOf(s)),
+		slog.String("phase", phase),
+		slog.Int64("duration_ms", d.Milliseconds()),
+	}
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	m.Slog.LogAttrs(ctx, level, "lifecycle", attrs...)
+}
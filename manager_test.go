@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errTestStop = errors.New("stop failed")
+
+// failingStop records its Stop through the embedded NullService and then
+// fails.
+type failingStop struct {
+	*NullService
+}
+
+func (f failingStop) Stop(ctx context.Context) error {
+	f.NullService.Stop(ctx)
+	return errTestStop
+}
+
+func TestStopAllContinuesPastFailureInOrder(t *testing.T) {
+	m, _ := newTestManager()
+	rec := &CallRecorder{}
+	m.Add(NewNull("A", rec))
+	m.Add(failingStop{NewNull("B", rec)})
+	m.Add(NewNull("C", rec))
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+
+	err := m.StopAll(context.Background())
+	want := []string{"start A", "start B", "start C", "stop C", "stop B", "stop A"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	var se *ServiceError
+	if !errors.As(err, &se) || se.ServiceName != "B" || !errors.Is(err, errTestStop) {
+		t.Errorf("StopAll error = %v, want B's stop failure", err)
+	}
+	// ExitStopFailure is the non-zero code for a run whose stop failed.
+	if code := ExitCodeOf(err); code != ExitStopFailure {
+		t.Errorf("ExitCodeOf = %d, want %d", code, ExitStopFailure)
+	}
+}
+
+func TestSplitBudget(t *testing.T) {
+	tests := []struct {
+		remaining time.Duration
+		left      int
+		want      time.Duration
+	}{
+		{remaining: 9 * time.Second, left: 3, want: 3 * time.Second},
+		{remaining: 9 * time.Second, left: 1, want: 9 * time.Second},
+		{remaining: 0, left: 3, want: 0},
+		{remaining: -time.Second, left: 3, want: 0},
+	}
+	for _, tt := range tests {
+		if got := splitBudget(tt.remaining, tt.left); got != tt.want {
+			t.Errorf("splitBudget(%v, %d) = %v, want %v", tt.remaining, tt.left, got, tt.want)
+		}
+	}
+
+	ctx, cancel := withTimeout(context.Background(), RealClock{}, splitBudget(0, 2))
+	defer cancel()
+	if ctx.Err() == nil {
+		t.Error("a zero share gave a context that hasn't expired")
+	}
+}
+
+// addOnStop registers another service from a second goroutine while its own
+// Stop is running.
+type addOnStop struct {
+	*NullService
+	m *Manager
+}
+
+func (a addOnStop) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.m.Add(NewNull("late", a.rec))
+	}()
+	<-done
+	return a.NullService.Stop(ctx)
+}
+
+func TestStopAllIgnoresServicesAddedDuringShutdown(t *testing.T) {
+	m, _ := newTestManager()
+	rec := &CallRecorder{}
+	m.Add(NewNull("A", rec))
+	m.Add(addOnStop{NewNull("B", rec), m})
+	m.Add(NewNull("C", rec))
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+	if err := m.StopAll(context.Background()); err != nil {
+		t.Fatalf("StopAll: %v", err)
+	}
+
+	want := []string{"start A", "start B", "start C", "stop C", "stop B", "stop A"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if _, ok := m.Get("late"); !ok {
+		t.Error("the service added during shutdown wasn't registered")
+	}
+}
+
+func TestShutdownOrder(t *testing.T) {
+	tests := []struct {
+		order ShutdownOrder
+		stops []string
+	}{
+		{OrderReverse, []string{"stop C", "stop B", "stop A"}},
+		{OrderForward, []string{"stop A", "stop B", "stop C"}},
+	}
+	for _, tt := range tests {
+		m, _ := newTestManager()
+		m.ShutdownOrder = tt.order
+		rec := &CallRecorder{}
+		for _, name := range []string{"A", "B", "C"} {
+			m.Add(NewNull(name, rec))
+		}
+		if err := m.StartAll(context.Background()); err != nil {
+			t.Fatalf("StartAll: %v", err)
+		}
+		if err := m.StopAll(context.Background()); err != nil {
+			t.Fatalf("StopAll: %v", err)
+		}
+		want := append([]string{"start A", "start B", "start C"}, tt.stops...)
+		if got := rec.Calls(); !slices.Equal(got, want) {
+			t.Errorf("order %d: calls = %v, want %v", tt.order, got, want)
+		}
+	}
+}
+
+func TestStartAllBudgets(t *testing.T) {
+	tests := []struct {
+		name         string
+		budget       time.Duration
+		startTimeout time.Duration
+		wantBudget   bool
+	}{
+		{name: "individual timeout", startTimeout: 20 * time.Millisecond},
+		{name: "global budget", budget: 50 * time.Millisecond, startTimeout: 5 * time.Second, wantBudget: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			logger := &testLogger{}
+			a := New("A", WithFakeDuration(0), WithLogger(logger))
+			m.Add(a)
+			m.Add(New("B", WithFakeDuration(time.Second), WithStartTimeout(tt.startTimeout), WithLogger(logger)))
+
+			ctx := context.Background()
+			if tt.budget > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.budget)
+				defer cancel()
+			}
+			err := m.StartAll(ctx)
+			if !errors.Is(err, ErrStartTimeout) {
+				t.Fatalf("StartAll error = %v, want a start timeout", err)
+			}
+			if got := strings.Contains(err.Error(), "startup budget exceeded"); got != tt.wantBudget {
+				t.Errorf("StartAll error = %v, blamed on the overall budget: %v, want %v", err, got, tt.wantBudget)
+			}
+			if a.IsRunning() || !logger.Contains("stopping service A") {
+				t.Error("A wasn't rolled back")
+			}
+		})
+	}
+}
+
+// peakCounter is shared by the probes of one test and tracks how many of
+// their Start calls were in flight at once.
+type peakCounter struct {
+	mu             sync.Mutex
+	inFlight, peak int
+}
+
+// startProbe is a service whose Start takes a moment and counts itself in
+// flight meanwhile.
+type startProbe struct {
+	name    string
+	counter *peakCounter
+}
+
+func (p startProbe) Name() string { return p.name }
+
+func (p startProbe) Start(ctx context.Context) error {
+	c := p.counter
+	c.mu.Lock()
+	c.inFlight++
+	c.peak = max(c.peak, c.inFlight)
+	c.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return nil
+}
+
+func (p startProbe) Stop(context.Context) error { return nil }
+
+func TestStartAllParallelRespectsConcurrencyLimit(t *testing.T) {
+	const limit = 3
+	m, _ := newTestManager()
+	counter := &peakCounter{}
+	for i := range 10 {
+		m.Add(startProbe{name: fmt.Sprintf("S%d", i), counter: counter})
+	}
+	if err := m.StartAllParallel(context.Background(), limit); err != nil {
+		t.Fatalf("StartAllParallel: %v", err)
+	}
+	if counter.peak > limit {
+		t.Errorf("peak concurrency = %d, want at most %d", counter.peak, limit)
+	}
+	if len(m.Started()) != 10 {
+		t.Errorf("started %d services, want 10", len(m.Started()))
+	}
+}
+
+func TestStopSkipsServicesThatAreNotRunning(t *testing.T) {
+	m, logger := newTestManager()
+	rec := &CallRecorder{}
+	m.Add(NewNull("A", rec))
+	m.Add(failingStart{NewNull("B", rec)})
+	if err := m.StartAll(context.Background()); err == nil {
+		t.Fatal("StartAll returned nil, want B's failure")
+	}
+	if err := m.StopAll(context.Background()); err != nil {
+		t.Fatalf("StopAll: %v", err)
+	}
+
+	want := []string{"start A", "start B", "stop A"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if !logger.Contains("[DEBUG] skipping stop of non-running service A") {
+		t.Errorf("skip not logged; got %q", logger.Lines())
+	}
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	tests := []struct {
+		name    string
+		parent  time.Duration // zero means no parent deadline
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "parent is earlier", parent: time.Second, timeout: 5 * time.Second, want: time.Second},
+		{name: "service is earlier", parent: 5 * time.Second, timeout: time.Second, want: time.Second},
+		{name: "no parent deadline", timeout: 2 * time.Second, want: 2 * time.Second},
+	}
+	const slack = 100 * time.Millisecond
+	near := func(got, want time.Time) bool {
+		d := got.Sub(want)
+		return d > -slack && d < slack
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			ms := New("A", WithFakeDuration(0), WithStartTimeout(tt.timeout), WithStopTimeout(tt.timeout))
+			m.Add(ms)
+			ctx := context.Background()
+			if tt.parent > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.parent)
+				defer cancel()
+			}
+
+			want := time.Now().Add(tt.want)
+			if err := m.StartAll(ctx); err != nil {
+				t.Fatalf("StartAll: %v", err)
+			}
+			if err := m.StopAll(ctx); err != nil {
+				t.Fatalf("StopAll: %v", err)
+			}
+			start, stop := ms.Deadlines()
+			if !near(start, want) {
+				t.Errorf("Start deadline %v from now, want %v", time.Until(start).Round(time.Millisecond), tt.want)
+			}
+			if !near(stop, want) {
+				t.Errorf("Stop deadline %v from now, want %v", time.Until(stop).Round(time.Millisecond), tt.want)
+			}
+		})
+	}
+}
+
+func TestStartAllCancelledAfterFirstService(t *testing.T) {
+	m, _ := newTestManager()
+	rec := &CallRecorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Add(&cancelOnStart{NewNull("A", rec), cancel})
+	m.Add(NewNull("B", rec))
+	m.Add(NewNull("C", rec))
+
+	if err := m.StartAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("StartAll error = %v, want context.Canceled", err)
+	}
+	want := []string{"start A", "stop A"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+// cancelOnStart starts and then cancels the startup it is part of.
+type cancelOnStart struct {
+	*NullService
+	cancel context.CancelFunc
+}
+
+func (c cancelOnStart) Start(ctx context.Context) error {
+	err := c.NullService.Start(ctx)
+	c.cancel()
+	return err
+}
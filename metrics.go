@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"time"
+)
+
+// Metrics holds how long each service's last Start and Stop call took,
+// keyed by service name. Failed calls are recorded too.
+type Metrics struct {
+	StartDurations map[string]time.Duration
+	StopDurations  map[string]time.Duration
+}
+
+// Metrics returns a snapshot of the durations recorded so far.
+func (m *Manager) Metrics() Metrics {
+	m.metricsMu.Lock()
+	defer m.metricsMu.Unlock()
+	return Metrics{
+		StartDurations: maps.Clone(m.metrics.StartDurations),
+		StopDurations:  maps.Clone(m.metrics.StopDurations),
+	}
+}
+
+func (m *Manager) recordDuration(s Service, phase string, d time.Duration) {
+	m.metricsMu.Lock()
+	defer m.metricsMu.Unlock()
+	durations := &m.metrics.StartDurations
+	if phase == PhaseStop {
+		durations = &m.metrics.StopDurations
+	}
+	if *durations == nil {
+		*durations = make(map[string]time.Duration)
+	}
+	(*durations)[m.nameOf(s)] = d
+}
+
+// labelEscaper escapes a Prometheus label value.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes the recorded durations and whether each service is
+// up as gauges in the Prometheus text exposition format. Services with no
+// recorded Start or Stop are left out of that duration gauge.
+func (m *Manager) WritePrometheus(w io.Writer) error {
+	metrics := m.Metrics()
+	services := m.registered()
+	names := make([]string, len(services))
+	for i, s := range services {
+		names[i] = labelEscaper.Replace(m.nameOf(s))
+	}
+
+	bw := bufio.NewWriter(w)
+	gauge := func(name, help string, value func(i int) (float64, bool)) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for i := range services {
+			if v, ok := value(i); ok {
+				fmt.Fprintf(bw, "%s{service=\"%s\"} %g\n", name, names[i], v)
+			}
+		}
+	}
+	duration := func(durations map[string]time.Duration) func(int) (float64, bool) {
+		return func(i int) (float64, bool) {
+			d, ok := durations[m.nameOf(services[i])]
+			return d.Seconds(), ok
+		}
+	}
+	gauge("service_start_duration_seconds", "How long the last Start call took.", duration(metrics.StartDurations))
+	gauge("service_stop_duration_seconds", "How long the last Stop call took.", duration(metrics.StopDurations))
+	gauge("service_up", "Whether the service is running.", func(i int) (float64, bool) {
+		if m.isUp(services[i]) {
+			return 1, true
+		}
+		return 0, true
+	})
+	return bw.Flush()
+}
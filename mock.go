@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DelayForever, used as a start or stop delay, makes the fake work never
+// finish so the call always ends on its context's deadline.
+const DelayForever time.Duration = -1
+
+var errInjected = errors.New("injected failure")
+
+// MockService pretends to do work by sleeping for fakeDuration on both start
+// and stop.
+type MockService struct {
+	name         string
+	fakeDuration time.Duration
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+
+	failOnStart  bool
+	failOnStop   bool
+	panicOnStart bool
+	startDelay   time.Duration
+	stopDelay    time.Duration
+	healthCheck  func(ctx context.Context) error
+	warmup       time.Duration
+	readyDelay   time.Duration
+	failOnWarmup bool
+	runFailure   time.Duration
+	policy       FailurePolicy
+	labels       map[string]string
+	priority     int
+	optional     bool
+	critical     bool
+
+	logger Logger
+	clock  Clock
+
+	mu            sync.Mutex
+	state         State
+	startDeadline time.Time
+	ready         chan struct{}
+	stopDeadline  time.Time
+}
+
+// DefaultFakeDuration is how long a MockService pretends to work unless
+// WithFakeDuration says otherwise.
+const DefaultFakeDuration = time.Second
+
+// Option configures a MockService.
+type Option func(*MockService)
+
+// WithFakeDuration sets how long Start and Stop pretend to work.
+func WithFakeDuration(d time.Duration) Option {
+	return func(ms *MockService) { ms.fakeDuration = d }
+}
+
+// WithStartTimeout sets the service's start budget.
+func WithStartTimeout(d time.Duration) Option {
+	return func(ms *MockService) { ms.startTimeout = d }
+}
+
+// WithStopTimeout sets the service's stop budget.
+func WithStopTimeout(d time.Duration) Option {
+	return func(ms *MockService) { ms.stopTimeout = d }
+}
+
+// WithFailOnStart makes Start fail immediately with a ServiceError.
+func WithFailOnStart() Option {
+	return func(ms *MockService) { ms.failOnStart = true }
+}
+
+// WithFailOnStop makes Stop fail immediately with a ServiceError.
+func WithFailOnStop() Option {
+	return func(ms *MockService) { ms.failOnStop = true }
+}
+
+// WithPanicOnStart makes Start panic.
+func WithPanicOnStart() Option {
+	return func(ms *MockService) { ms.panicOnStart = true }
+}
+
+// WithStartDelay overrides the fake duration for Start. DelayForever makes
+// Start always run into its deadline.
+func WithStartDelay(d time.Duration) Option {
+	return func(ms *MockService) { ms.startDelay = d }
+}
+
+// WithStopDelay overrides the fake duration for Stop. DelayForever makes
+// Stop always run into its deadline.
+func WithStopDelay(d time.Duration) Option {
+	return func(ms *MockService) { ms.stopDelay = d }
+}
+
+// WithWarmup makes the service take d to warm up after starting.
+func WithWarmup(d time.Duration) Option {
+	return func(ms *MockService) { ms.warmup = d }
+}
+
+// WithFailOnWarmup makes Warmup fail immediately with a ServiceError.
+func WithFailOnWarmup() Option {
+	return func(ms *MockService) { ms.failOnWarmup = true }
+}
+
+// WithReadyDelay makes the service become ready d after Start returns.
+// DelayForever makes it never become ready.
+func WithReadyDelay(d time.Duration) Option {
+	return func(ms *MockService) { ms.readyDelay = d }
+}
+
+// WithHealthCheck replaces the default always-healthy check.
+func WithHealthCheck(fn func(ctx context.Context) error) Option {
+	return func(ms *MockService) { ms.healthCheck = fn }
+}
+
+// WithRunFailure makes every Run call crash after d. Without it Run only
+// returns when its context is cancelled.
+func WithRunFailure(d time.Duration) Option {
+	return func(ms *MockService) { ms.runFailure = d }
+}
+
+// WithFailurePolicy chooses what the Manager does when Start times out.
+func WithFailurePolicy(p FailurePolicy) Option {
+	return func(ms *MockService) { ms.policy = p }
+}
+
+// WithLabels attaches labels used by HasLabel and StopWhere.
+func WithLabels(labels map[string]string) Option {
+	return func(ms *MockService) { ms.labels = labels }
+}
+
+// WithOptional marks the service Optional, so a failed start is skipped.
+func WithOptional() Option {
+	return func(ms *MockService) { ms.optional = true }
+}
+
+// WithCritical marks the service Critical, so failing a health check while
+// running shuts the program down.
+func WithCritical() Option {
+	return func(ms *MockService) { ms.critical = true }
+}
+
+// WithPriority sets the priority the service reports to the Manager.
+func WithPriority(p int) Option {
+	return func(ms *MockService) { ms.priority = p }
+}
+
+// WithClock makes the service measure its fake work on c.
+func WithClock(c Clock) Option {
+	return func(ms *MockService) { ms.clock = c }
+}
+
+// WithLogger redirects the service's own messages. Without it they go to
+// the logger carried by the context, which is the Manager's.
+func WithLogger(l Logger) Option {
+	return func(ms *MockService) { ms.logger = l }
+}
+
+// New returns a MockService with the given name. Without options it takes
+// DefaultFakeDuration to start and stop and uses the package default
+// budgets.
+func New(name string, opts ...Option) *MockService {
+	ms := &MockService{
+		name:         name,
+		fakeDuration: DefaultFakeDuration,
+		clock:        RealClock{},
+	}
+	for _, opt := range opts {
+		opt(ms)
+	}
+	return ms
+}
+
+func (ms *MockService) FailurePolicy() FailurePolicy { return ms.policy }
+
+func (ms *MockService) Labels() map[string]string { return ms.labels }
+
+func (ms *MockService) Priority() int { return ms.priority }
+
+func (ms *MockService) Optional() bool { return ms.optional }
+
+func (ms *MockService) Critical() bool { return ms.critical }
+
+// delay returns how long the fake work takes: override if set, fakeDuration
+// otherwise.
+func (ms *MockService) delay(override time.Duration) time.Duration {
+	if override != 0 {
+		return override
+	}
+	return ms.fakeDuration
+}
+
+func (ms *MockService) Name() string { return ms.name }
+
+func (ms *MockService) StartTimeout() time.Duration { return ms.startTimeout }
+
+func (ms *MockService) StopTimeout() time.Duration { return ms.stopTimeout }
+
+// State reports the current lifecycle state. It is safe to call while Start
+// or Stop is running.
+func (ms *MockService) State() State {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.state
+}
+
+// Deadlines returns the deadlines of the contexts the last Start and Stop
+// were called with; a zero time means that call had none or hasn't happened.
+// Comparing them with the caller's deadline and the service's timeouts shows
+// how the Manager composed the two.
+func (ms *MockService) Deadlines() (start, stop time.Time) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.startDeadline, ms.stopDeadline
+}
+
+// IsRunning reports whether the service has started and not yet stopped.
+func (ms *MockService) IsRunning() bool {
+	return ms.State() == StateRunning
+}
+
+func (ms *MockService) setState(st State) {
+	ms.mu.Lock()
+	ms.state = st
+	ms.mu.Unlock()
+}
+
+// transition moves to next only if the service is still in from, so a late
+// goroutine can't overwrite a state set after a timeout.
+func (ms *MockService) transition(from, next State) {
+	ms.mu.Lock()
+	if ms.state == from {
+		ms.state = next
+	}
+	ms.mu.Unlock()
+}
+
+func (ms *MockService) Start(ctx context.Context) error {
+	began := ms.clock.Now()
+	deadline, _ := ctx.Deadline()
+	ms.mu.Lock()
+	ms.startDeadline = deadline
+	ms.mu.Unlock()
+	ms.log(ctx).Infof("starting service %s%s", ms.name, runSuffix(ctx))
+	if ms.panicOnStart {
+		ms.setState(StateFailed)
+		panic("mock service " + ms.name + " panicked on start")
+	}
+	if ms.failOnStart {
+		ms.setState(StateFailed)
+		return &ServiceError{ServiceName: ms.name, Phase: PhaseStart, Err: errInjected}
+	}
+	ms.setState(StateStarting)
+
+	d := ms.delay(ms.startDelay)
+	if v, ok := configDuration(ctx, "fakeDuration"); ok && ms.startDelay == 0 {
+		d = v
+	}
+	doneStarting := make(chan struct{}, 1)
+	if d != DelayForever {
+		go func() {
+			if sleep(ctx, ms.clock, d) != nil {
+				return
+			}
+			ms.transition(StateStarting, StateRunning)
+			doneStarting <- struct{}{}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		ms.transition(StateStarting, StateFailed)
+		return &ServiceError{ServiceName: ms.name, Phase: PhaseStart, Err: doneErr(ctx), Detail: budgetDetail(ctx, began, ms.clock.Now())}
+	case <-doneStarting:
+		ms.log(ctx).Infof("service %s started", ms.name)
+		ms.becomeReady()
+		return nil
+	}
+}
+
+// Ready is closed once the service has finished starting up in the
+// background, WithReadyDelay after Start returned.
+func (ms *MockService) Ready() <-chan struct{} {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.ready
+}
+
+// becomeReady replaces the ready channel and closes it after readyDelay.
+func (ms *MockService) becomeReady() {
+	ready := make(chan struct{})
+	ms.mu.Lock()
+	ms.ready = ready
+	ms.mu.Unlock()
+	switch d := ms.readyDelay; {
+	case d == DelayForever:
+	case d <= 0:
+		close(ready)
+	default:
+		go func() {
+			<-ms.clock.After(d)
+			close(ready)
+		}()
+	}
+}
+
+func (ms *MockService) Warmup(ctx context.Context) error {
+	if ms.warmup == 0 && !ms.failOnWarmup {
+		return nil
+	}
+	ms.log(ctx).Infof("warming up service %s%s", ms.name, runSuffix(ctx))
+	if ms.failOnWarmup {
+		return &ServiceError{ServiceName: ms.name, Phase: PhaseWarmup, Err: errInjected}
+	}
+	began := ms.clock.Now()
+	if sleep(ctx, ms.clock, ms.warmup) != nil {
+		return &ServiceError{ServiceName: ms.name, Phase: PhaseWarmup, Err: doneErr(ctx), Detail: budgetDetail(ctx, began, ms.clock.Now())}
+	}
+	ms.log(ctx).Infof("service %s warmed up", ms.name)
+	return nil
+}
+
+func (ms *MockService) HealthCheck(ctx context.Context) error {
+	if ms.healthCheck == nil {
+		return nil
+	}
+	if err := ms.healthCheck(ctx); err != nil {
+		return &ServiceError{ServiceName: ms.name, Phase: PhaseHealth, Err: err}
+	}
+	return nil
+}
+
+func (ms *MockService) Run(ctx context.Context) error {
+	if ms.runFailure == 0 {
+		<-ctx.Done()
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-ms.clock.After(ms.runFailure):
+		return errInjected
+	}
+}
+
+// Stop is idempotent: only a running service does any work, every other
+// call returns nil straight away.
+func (ms *MockService) Stop(ctx context.Context) error {
+	began := ms.clock.Now()
+	ms.mu.Lock()
+	ms.stopDeadline, _ = ctx.Deadline()
+	if ms.state != StateRunning {
+		ms.mu.Unlock()
+		return nil
+	}
+	ms.state = StateStopping
+	ms.mu.Unlock()
+
+	ms.log(ctx).Infof("stopping service %s%s%s", ms.name, runSuffix(ctx), reasonSuffix(ctx))
+	if ms.failOnStop {
+		ms.setState(StateFailed)
+		return &ServiceError{ServiceName: ms.name, Phase: PhaseStop, Err: errInjected}
+	}
+
+	doneStopping := make(chan struct{}, 1)
+	if d := ms.delay(ms.stopDelay); d != DelayForever {
+		go func() {
+			if sleep(ctx, ms.clock, d) != nil {
+				return
+			}
+			ms.transition(StateStopping, StateStopped)
+			doneStopping <- struct{}{}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		ms.transition(StateStopping, StateFailed)
+		return &ServiceError{ServiceName: ms.name, Phase: PhaseStop, Err: doneErr(ctx), Detail: budgetDetail(ctx, began, ms.clock.Now())}
+	case <-doneStopping:
+		ms.log(ctx).Infof("service %s stopped", ms.name)
+		return nil
+	}
+}
+
+// configDuration reads key from the service configuration carried by ctx as
+// a duration string such as "1.5s".
+func configDuration(ctx context.Context, key string) (time.Duration, bool) {
+	s, ok := ServiceConfigFromContext(ctx)[key].(string)
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+// log returns the logger set with WithLogger, or else the one carried by ctx.
+func (ms *MockService) log(ctx context.Context) Logger {
+	if ms.logger != nil {
+		return ms.logger
+	}
+	return LoggerFromContext(ctx)
+}
+
+// runSuffix formats the run ID carried by ctx for appending to a log line.
+func runSuffix(ctx context.Context) string {
+	if id := RunIDFromContext(ctx); id != "" {
+		return " (run " + id + ")"
+	}
+	return ""
+}
+
+// budgetDetail describes the budget ctx gave a call that began at began and
+// how much of it was used by now, e.g. "budget 3s, elapsed 3.0s".
+func budgetDetail(ctx context.Context, began, now time.Time) string {
+	elapsed := fmt.Sprintf("elapsed %.1fs", now.Sub(began).Seconds())
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return elapsed
+	}
+	return fmt.Sprintf("budget %v, %s", deadline.Sub(began).Round(10*time.Millisecond), elapsed)
+}
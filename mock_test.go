@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMockServiceConcurrentStartAndIsRunning(t *testing.T) {
+	ms := New("A", WithFakeDuration(20*time.Millisecond), WithLogger(&testLogger{}))
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					ms.IsRunning()
+				}
+			}
+		}()
+	}
+
+	err := ms.Start(context.Background())
+	close(done)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !ms.IsRunning() {
+		t.Fatal("IsRunning is false after Start returned nil")
+	}
+}
+
+func TestMockServiceStartTimeoutDoesNotLeak(t *testing.T) {
+	// Far longer than settleGoroutines waits, so a sleeper that ignores ctx
+	// is caught.
+	ms := New("A", WithFakeDuration(5*time.Second), WithLogger(&testLogger{}))
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := ms.Start(ctx); err == nil {
+		t.Fatal("Start returned nil, want a timeout")
+	}
+	if n := settleGoroutines(before); n > before {
+		t.Errorf("goroutines: %d before Start, %d after", before, n)
+	}
+}
+
+func TestMockServiceSleepHonorsContext(t *testing.T) {
+	ms := New("A", WithFakeDuration(60*time.Second), WithLogger(&testLogger{}))
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	began := time.Now()
+	ms.Start(ctx)
+	if took := time.Since(began); took > time.Second {
+		t.Errorf("Start took %v with a 50ms context", took)
+	}
+	if n := settleGoroutines(before); n > before {
+		t.Errorf("fake work still sleeping after the deadline: %d goroutines, want %d", n, before)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// CallRecorder collects the calls made on the NullServices sharing it, in
+// the order they happened, as entries like "start A" and "stop A".
+type CallRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+// Calls returns the recorded calls so far.
+func (r *CallRecorder) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
+
+func (r *CallRecorder) record(call string) {
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+}
+
+// NullService does nothing on Start and Stop except record the call, which
+// makes ordering checks deterministic.
+type NullService struct {
+	name string
+	rec  *CallRecorder
+}
+
+// NewNull returns a NullService called name that records into rec.
+func NewNull(name string, rec *CallRecorder) *NullService {
+	return &NullService{name: name, rec: rec}
+}
+
+func (ns *NullService) Name() string { return ns.name }
+
+func (ns *NullService) Start(context.Context) error {
+	ns.rec.record("start " + ns.name)
+	return nil
+}
+
+func (ns *NullService) Stop(context.Context) error {
+	ns.rec.record("stop " + ns.name)
+	return nil
+}
+
+// DrainableNullService is a NullService that also records Drain calls, as
+// "drain A".
+type DrainableNullService struct {
+	NullService
+}
+
+// NewDrainableNull returns a DrainableNullService called name that records
+// into rec.
+func NewDrainableNull(name string, rec *CallRecorder) *DrainableNullService {
+	return &DrainableNullService{NullService{name: name, rec: rec}}
+}
+
+func (ns *DrainableNullService) Drain(context.Context) error {
+	ns.rec.record("drain " + ns.name)
+	return nil
+}
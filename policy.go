@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// FailurePolicy decides what happens when a service's Start runs out of
+// time.
+type FailurePolicy int
+
+const (
+	// PolicyAbort unwinds the whole startup. It is the default.
+	PolicyAbort FailurePolicy = iota
+	// PolicySkip leaves the service down and carries on with the next one.
+	// A skipped service is not stopped during shutdown.
+	PolicySkip
+)
+
+// FailurePolicies is implemented by services that choose their own
+// FailurePolicy.
+type FailurePolicies interface {
+	FailurePolicy() FailurePolicy
+}
+
+func failurePolicyOf(s Service) FailurePolicy {
+	if p, ok := s.(FailurePolicies); ok {
+		return p.FailurePolicy()
+	}
+	return PolicyAbort
+}
+
+// Optional is implemented by services that are nice to have. If an optional
+// service fails to start, for any reason, it is skipped as under PolicySkip
+// instead of aborting the startup.
+type Optional interface {
+	Optional() bool
+}
+
+func isOptional(s Service) bool {
+	o, ok := s.(Optional)
+	return ok && o.Optional()
+}
+
+// isTimeout reports whether err means a call ran out of time.
+func isTimeout(err error) bool {
+	return errors.Is(err, errTimeLimit) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Skipped returns the services the last startup skipped under PolicySkip or
+// because they are Optional.
+func (m *Manager) Skipped() []Service {
+	m.startedMu.Lock()
+	defer m.startedMu.Unlock()
+	return slices.Clone(m.skipped)
+}
+
+// startOrSkip starts s and reports whether it came up. A timeout under
+// PolicySkip, or any failure of an Optional service, is logged and
+// swallowed, unless ctx itself was cancelled.
+func (m *Manager) startOrSkip(ctx context.Context, s Service) (bool, error) {
+	err := m.start(ctx, s)
+	if err == nil {
+		return true, nil
+	}
+	skip := isOptional(s) || isTimeout(err) && failurePolicyOf(s) == PolicySkip
+	if skip && ctx.Err() == nil {
+		m.Logger.Warnf("skipping service %s: %v", m.nameOf(s), err)
+		m.startedMu.Lock()
+		m.skipped = append(m.skipped, s)
+		m.startedMu.Unlock()
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOptionalServiceFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		optional bool
+		want     ExitCode
+	}{
+		{name: "optional", optional: true, want: ExitClean},
+		// A failed required start exits with ExitStartFailure.
+		{name: "required", want: ExitStartFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			m.MaxUptime = 50 * time.Millisecond
+			a := New("A", WithFakeDuration(0))
+			m.Add(a)
+			opts := []Option{WithFailOnStart(), WithRunFailure(time.Millisecond)}
+			if tt.optional {
+				opts = append(opts, WithOptional())
+			}
+			m.Add(New("B", opts...))
+			c := New("C", WithFakeDuration(0))
+			m.Add(c)
+
+			_, err := m.Run(context.Background())
+			if code := ExitCodeOf(err); code != tt.want {
+				t.Errorf("ExitCodeOf(%v) = %d, want %d", err, code, tt.want)
+			}
+			started := len(m.Started())
+			if tt.optional && started != 2 {
+				t.Errorf("%d services started, want A and C", started)
+			}
+			if !tt.optional && started != 1 {
+				t.Errorf("%d services started, want only A", started)
+			}
+			if a.IsRunning() || c.IsRunning() {
+				t.Error("a service was left running")
+			}
+		})
+	}
+}
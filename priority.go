@@ -0,0 +1,37 @@
+package main
+
+import "slices"
+
+// Prioritized is implemented by services that want to start earlier or later
+// than their registration order suggests. Lower priorities start first and
+// stop last.
+type Prioritized interface {
+	Priority() int
+}
+
+// priorityOf returns s's Priority, or 0 if it doesn't declare one.
+func priorityOf(s Service) int {
+	if p, ok := s.(Prioritized); ok {
+		return p.Priority()
+	}
+	return 0
+}
+
+// stagePriority is the lowest priority of any service in stage.
+func stagePriority(stage []Service) int {
+	lowest := priorityOf(stage[0])
+	for _, s := range stage[1:] {
+		lowest = min(lowest, priorityOf(s))
+	}
+	return lowest
+}
+
+// sortByPriority orders stages by ascending priority, keeping registration
+// order for ties. Once any service declares a priority this replaces raw
+// registration order; if none does, stages are returned as they are.
+func sortByPriority(stages [][]Service) [][]Service {
+	slices.SortStableFunc(stages, func(a, b []Service) int {
+		return stagePriority(a) - stagePriority(b)
+	})
+	return stages
+}
@@ -0,0 +1,26 @@
+package main
+
+import "time"
+
+// DefaultProgressInterval is the ProgressInterval set by NewManager.
+const DefaultProgressInterval = 5 * time.Second
+
+// reportProgress logs a heartbeat for s every ProgressInterval until the
+// returned function is called. It does nothing if the interval is zero.
+func (m *Manager) reportProgress(s Service, began time.Time) (done func()) {
+	if m.ProgressInterval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-m.Clock.After(m.ProgressInterval):
+				m.Logger.Infof("still starting service %s (%v elapsed)", m.nameOf(s), m.Clock.Now().Sub(began).Round(time.Second))
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
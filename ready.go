@@ -0,0 +1,29 @@
+package main
+
+import "context"
+
+// Readier is implemented by services that keep starting up in the background
+// after Start returns, such as servers that bind at once but load lazily.
+// The Manager counts such a service as up only once Ready is closed.
+type Readier interface {
+	Ready() <-chan struct{}
+}
+
+// awaitReady waits for s's Ready channel, if it has one, until ctx, which
+// carries the rest of s's start budget, is done. A service that doesn't get
+// ready in time has failed to start and is stopped again.
+func (m *Manager) awaitReady(ctx context.Context, s Service) error {
+	r, ok := s.(Readier)
+	if !ok {
+		return nil
+	}
+	select {
+	case <-r.Ready():
+		return nil
+	case <-ctx.Done():
+	}
+	err := &ServiceError{ServiceName: m.nameOf(s), Phase: PhaseStart, Err: doneErr(ctx), Detail: "waiting for ready"}
+	m.runOnError(err)
+	m.unwindOne(ctx, s)
+	return err
+}
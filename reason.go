@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"os"
+)
+
+// ShutdownReason says why services are being stopped.
+type ShutdownReason int
+
+const (
+	// ReasonRequested means Shutdown was called or Run's context was
+	// cancelled.
+	ReasonRequested ShutdownReason = iota
+	// ReasonSignal means one of the Manager's stop signals arrived; Stop can
+	// tell which from SignalFromContext.
+	ReasonSignal
+	// ReasonStartFailed means startup failed and the services that came up
+	// are being rolled back.
+	ReasonStartFailed
+	// ReasonMaxUptime means MaxUptime elapsed.
+	ReasonMaxUptime
+	// ReasonFailure means a service failed during the working phase.
+	ReasonFailure
+	// ReasonUnhealthy means a Critical service failed a health check during
+	// the working phase.
+	ReasonUnhealthy
+)
+
+func (r ShutdownReason) String() string {
+	switch r {
+	case ReasonRequested:
+		return "requested"
+	case ReasonSignal:
+		return "signal"
+	case ReasonStartFailed:
+		return "start failed"
+	case ReasonMaxUptime:
+		return "max uptime"
+	case ReasonFailure:
+		return "failure"
+	case ReasonUnhealthy:
+		return "unhealthy"
+	}
+	return "unknown"
+}
+
+type reasonKey struct{}
+
+// WithShutdownReason returns a copy of ctx carrying r.
+func WithShutdownReason(ctx context.Context, r ShutdownReason) context.Context {
+	return context.WithValue(ctx, reasonKey{}, r)
+}
+
+// ReasonFromContext returns the shutdown reason carried by ctx. The Manager
+// attaches one to every context it hands to Stop while shutting down or
+// rolling back; ok is false for other contexts, such as a Reload.
+func ReasonFromContext(ctx context.Context) (r ShutdownReason, ok bool) {
+	r, ok = ctx.Value(reasonKey{}).(ShutdownReason)
+	return r, ok
+}
+
+type signalKey struct{}
+
+// WithSignal returns a copy of ctx carrying sig.
+func WithSignal(ctx context.Context, sig os.Signal) context.Context {
+	return context.WithValue(ctx, signalKey{}, sig)
+}
+
+// SignalFromContext returns the signal that began shutdown, if ctx carries
+// one. The Manager attaches it alongside ReasonSignal.
+func SignalFromContext(ctx context.Context) (sig os.Signal, ok bool) {
+	sig, ok = ctx.Value(signalKey{}).(os.Signal)
+	return sig, ok
+}
+
+// TriggerShutdown makes a blocked Run proceed to its stop phase as if a
+// signal had arrived, with r as the reason Stop sees. It is for programs that
+// drive the lifecycle without OS signals. Only the first call, or the first
+// of it, Shutdown and a signal, takes effect.
+func (m *Manager) TriggerShutdown(r ShutdownReason) {
+	m.shutdown(r)
+}
+
+// shutdown begins shutdown for reason r. Only the first reason is kept.
+func (m *Manager) shutdown(r ShutdownReason) {
+	m.beginShutdown(r, nil)
+}
+
+// shutdownOnSignal begins shutdown with ReasonSignal, recording sig.
+func (m *Manager) shutdownOnSignal(sig os.Signal) {
+	m.beginShutdown(ReasonSignal, sig)
+}
+
+// beginShutdown closes done, recording r and sig, unless it is already
+// closed.
+func (m *Manager) beginShutdown(r ShutdownReason, sig os.Signal) {
+	m.shutdownMu.Lock()
+	defer m.shutdownMu.Unlock()
+	select {
+	case <-m.done:
+		return
+	default:
+	}
+	m.reason, m.signal = r, sig
+	close(m.done)
+}
+
+// withReason returns a copy of ctx carrying r and, if shutdown began on a
+// signal, that signal.
+func (m *Manager) withReason(ctx context.Context, r ShutdownReason) context.Context {
+	ctx = WithShutdownReason(ctx, r)
+	m.shutdownMu.Lock()
+	sig := m.signal
+	m.shutdownMu.Unlock()
+	if r == ReasonSignal && sig != nil {
+		ctx = WithSignal(ctx, sig)
+	}
+	return ctx
+}
+
+// shutdownReason returns why shutdown began, or ReasonRequested if it hasn't
+// been begun through Shutdown or Run.
+func (m *Manager) shutdownReason() ShutdownReason {
+	m.shutdownMu.Lock()
+	defer m.shutdownMu.Unlock()
+	select {
+	case <-m.done:
+		return m.reason
+	default:
+		return ReasonRequested
+	}
+}
+
+// reasonSuffix formats the shutdown reason carried by ctx for appending to a
+// log line.
+func reasonSuffix(ctx context.Context) string {
+	if r, ok := ReasonFromContext(ctx); ok {
+		if sig, ok := SignalFromContext(ctx); ok {
+			return ", reason: " + r.String() + " (" + sig.String() + ")"
+		}
+		return ", reason: " + r.String()
+	}
+	return ""
+}
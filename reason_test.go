@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStopSeesShutdownReason(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(m *Manager, a *MockService)
+		want  string
+	}{
+		{
+			name: "signal",
+			setup: func(m *Manager, a *MockService) {
+				m.NotifyOn(syscall.SIGUSR1)
+				m.Signals()
+				go func() {
+					waitFor(a.IsRunning)
+					syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+				}()
+			},
+			want: "reason: signal (user defined signal 1)",
+		},
+		{
+			name: "start failed",
+			setup: func(m *Manager, a *MockService) {
+				m.Add(New("B", WithFailOnStart()))
+			},
+			want: "reason: start failed",
+		},
+		{
+			name: "max uptime",
+			setup: func(m *Manager, a *MockService) {
+				m.MaxUptime = 20 * time.Millisecond
+			},
+			want: "reason: max uptime",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			logger := &testLogger{}
+			a := New("A", WithFakeDuration(0), WithLogger(logger))
+			m.Add(a)
+			tt.setup(m, a)
+			m.Run(context.Background())
+			if !logger.Contains("stopping service A") || !logger.Contains(tt.want) {
+				t.Errorf("A's stop didn't log %q; got %q", tt.want, logger.Lines())
+			}
+		})
+	}
+}
+
+// signalRecorder keeps the signal its Stop context carries.
+type signalRecorder struct {
+	*NullService
+	got chan os.Signal
+}
+
+func (s signalRecorder) Stop(ctx context.Context) error {
+	if sig, ok := SignalFromContext(ctx); ok {
+		s.got <- sig
+	}
+	return s.NullService.Stop(ctx)
+}
+
+func TestSignalIsLoggedAndPassedToStop(t *testing.T) {
+	m, logger := newTestManager()
+	rec := &CallRecorder{}
+	s := signalRecorder{NewNull("A", rec), make(chan os.Signal, 1)}
+	m.Add(s)
+	m.NotifyOn(syscall.SIGTERM)
+	m.Signals()
+	go func() {
+		waitFor(func() bool { return len(rec.Calls()) == 1 })
+		syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}()
+
+	if _, err := m.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !logger.Contains("[INFO] received signal terminated, shutting down") {
+		t.Errorf("signal name not logged; got %q", logger.Lines())
+	}
+	select {
+	case sig := <-s.got:
+		if sig != syscall.SIGTERM {
+			t.Errorf("Stop saw %v, want SIGTERM", sig)
+		}
+	default:
+		t.Error("Stop's context carried no signal")
+	}
+}
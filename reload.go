@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Reload restarts the started service called name by stopping and starting
+// it again, each under the service's own timeouts. It only works during the
+// working phase. If the restart fails, the Manager shuts down and Run
+// reports the failure. Reloads are serialized.
+func (m *Manager) Reload(ctx context.Context, name string) error {
+	m.changeMu.Lock()
+	defer m.changeMu.Unlock()
+	if err := m.canChange("reload"); err != nil {
+		return err
+	}
+
+	var svc Service
+	for _, s := range m.Started() {
+		if m.nameOf(s) == name {
+			svc = s
+			break
+		}
+	}
+	if svc == nil {
+		return fmt.Errorf("no started service named %s", name)
+	}
+
+	m.Logger.Infof("reloading service %s", name)
+	ctx = m.withRun(ctx)
+	err := m.stop(ctx, svc)
+	if err == nil {
+		err = m.start(ctx, svc)
+	}
+	if err != nil {
+		err = &ServiceError{ServiceName: name, Phase: PhaseReload, Err: err}
+		m.Logger.Errorf("%v", err)
+		m.fail(err)
+		return err
+	}
+	return nil
+}
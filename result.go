@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ResultEnv names the environment variable main reads Manager.ResultPath
+// from.
+const ResultEnv = "LIFECYCLE_RESULT"
+
+// Result summarizes one run of the lifecycle, one entry per registered
+// service in registration order.
+type Result struct {
+	Services []ServiceResult
+}
+
+// ServiceResult is what happened to one service during a run. A service
+// whose Start was never called has Started false and zero start fields.
+type ServiceResult struct {
+	Name          string
+	State         State
+	Started       bool
+	Stopped       bool
+	StartDuration time.Duration
+	StopDuration  time.Duration
+	StartErr      error
+	StopErr       error
+}
+
+// Err joins the start and stop errors of r.
+func (r ServiceResult) Err() error {
+	return errors.Join(r.StartErr, r.StopErr)
+}
+
+// Result returns the summary of the current or last run.
+func (m *Manager) Result() Result {
+	m.metricsMu.Lock()
+	outcomes := make(map[string]ServiceResult, len(m.outcomes))
+	for name, r := range m.outcomes {
+		outcomes[name] = *r
+	}
+	m.metricsMu.Unlock()
+
+	var res Result
+	for _, s := range m.registered() {
+		name := m.nameOf(s)
+		r, ok := outcomes[name]
+		if !ok {
+			r = ServiceResult{Name: name}
+		}
+		switch {
+		case r.StartErr != nil || r.StopErr != nil:
+			r.State = StateFailed
+		case r.Started && !r.Stopped:
+			r.State = StateRunning
+		default:
+			r.State = StateStopped
+		}
+		if st, ok := s.(Stateful); ok {
+			r.State = st.State()
+		}
+		res.Services = append(res.Services, r)
+	}
+	return res
+}
+
+// recordOutcome folds a finished Start or Stop call into the run's results.
+func (m *Manager) recordOutcome(s Service, phase string, d time.Duration, err error) {
+	name := m.nameOf(s)
+	m.metricsMu.Lock()
+	defer m.metricsMu.Unlock()
+	if m.outcomes == nil {
+		m.outcomes = make(map[string]*ServiceResult)
+	}
+	r, ok := m.outcomes[name]
+	if !ok {
+		r = &ServiceResult{Name: name}
+		m.outcomes[name] = r
+	}
+	switch phase {
+	case PhaseStart:
+		r.StartDuration, r.StartErr = d, err
+		r.Started = err == nil
+		if r.Started {
+			r.Stopped = false
+		}
+	case PhaseStop:
+		r.StopDuration, r.StopErr = d, err
+		r.Stopped = err == nil
+	}
+}
+
+func (m *Manager) resetOutcomes() {
+	m.metricsMu.Lock()
+	m.outcomes = nil
+	m.metricsMu.Unlock()
+}
+
+type resultJSON struct {
+	Services []serviceResultJSON `json:"services"`
+}
+
+type serviceResultJSON struct {
+	Name            string     `json:"name"`
+	State           string     `json:"state"`
+	Started         bool       `json:"started"`
+	Stopped         bool       `json:"stopped"`
+	StartDurationMS int64      `json:"start_duration_ms"`
+	StopDurationMS  int64      `json:"stop_duration_ms"`
+	StartError      *errorJSON `json:"start_error,omitempty"`
+	StopError       *errorJSON `json:"stop_error,omitempty"`
+}
+
+// errorJSON is an error as its message plus, if it is or wraps a
+// ServiceError, that error's fields.
+type errorJSON struct {
+	Message string `json:"message"`
+	Service string `json:"service,omitempty"`
+	Phase   string `json:"phase,omitempty"`
+	Cause   string `json:"cause,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+func newErrorJSON(err error) *errorJSON {
+	if err == nil {
+		return nil
+	}
+	e := &errorJSON{Message: err.Error()}
+	var se *ServiceError
+	if errors.As(err, &se) {
+		e.Service, e.Phase, e.Detail = se.ServiceName, se.Phase, se.Detail
+		if se.Err != nil {
+			e.Cause = se.Err.Error()
+		}
+	}
+	return e
+}
+
+// WriteResult writes the current Result to path as JSON, one object per
+// service with its final state, durations in milliseconds and errors.
+func (m *Manager) WriteResult(path string) error {
+	var out resultJSON
+	for _, r := range m.Result().Services {
+		out.Services = append(out.Services, serviceResultJSON{
+			Name:            r.Name,
+			State:           r.State.String(),
+			Started:         r.Started,
+			Stopped:         r.Stopped,
+			StartDurationMS: r.StartDuration.Milliseconds(),
+			StopDurationMS:  r.StopDuration.Milliseconds(),
+			StartError:      newErrorJSON(r.StartErr),
+			StopError:       newErrorJSON(r.StopErr),
+		})
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode result: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// StartMissing starts, in the same order as StartAll, only the services that
+// aren't running: they were never started, failed, or were stopped. Running
+// ones are skipped with a log line. It is meant for bringing a partly failed
+// startup the rest of the way up without restarting the process. On failure
+// only the services this call brought up are stopped again; the ones that
+// were already running are left alone. Like AddAndStart, it fails once
+// shutdown has begun, and calls to it are serialized with other runtime
+// changes.
+func (m *Manager) StartMissing(ctx context.Context) error {
+	m.changeMu.Lock()
+	defer m.changeMu.Unlock()
+	if err := m.canChange("start missing services"); err != nil {
+		return err
+	}
+	plan, err := m.startPlan()
+	if err != nil {
+		return err
+	}
+	if m.currentRunID() == "" {
+		ctx = m.newRun(ctx)
+	} else {
+		ctx = m.withRun(ctx)
+	}
+
+	var brought [][]Service
+	unwind := func() {
+		stopCtx, cancel := m.rollbackCtx(ctx)
+		defer cancel()
+		m.stopReverse(stopCtx, brought)
+	}
+	for _, stage := range plan {
+		var todo []Service
+		for _, s := range stage {
+			if m.isUp(s) {
+				m.Logger.Infof("service %s already running, skipping", m.nameOf(s))
+				continue
+			}
+			todo = append(todo, s)
+		}
+		if len(todo) == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			unwind()
+			return fmt.Errorf("startup aborted: %w", ctx.Err())
+		default:
+		}
+		up, err := m.startStage(ctx, todo, 0)
+		m.restarted(up)
+		if len(up) > 0 {
+			brought = append(brought, up)
+		}
+		if err != nil {
+			unwind()
+			return err
+		}
+	}
+	if err := m.checkHealth(ctx, slices.Concat(brought...)); err != nil {
+		unwind()
+		return err
+	}
+	return nil
+}
+
+// restarted records stage as started, first dropping its services from
+// wherever an earlier startup left them in the started list, so that none
+// is listed, or stopped, twice.
+func (m *Manager) restarted(stage []Service) {
+	if len(stage) == 0 {
+		return
+	}
+	m.startedMu.Lock()
+	for _, s := range stage {
+		m.started = removeFromStages(m.started, s)
+	}
+	m.started = append(m.started, stage)
+	m.startedMu.Unlock()
+}
+
+// isUp reports whether s is running: by its State if it is Stateful, or else
+// by whether its last Start succeeded and no Stop has since.
+func (m *Manager) isUp(s Service) bool {
+	if st, ok := s.(Stateful); ok {
+		return st.State() == StateRunning
+	}
+	name := m.nameOf(s)
+	m.metricsMu.Lock()
+	defer m.metricsMu.Unlock()
+	r, ok := m.outcomes[name]
+	return ok && r.Started && !r.Stopped
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// flakyStart fails its first Start and succeeds afterwards.
+type flakyStart struct {
+	*NullService
+	failed bool
+}
+
+func (f *flakyStart) Start(ctx context.Context) error {
+	f.NullService.Start(ctx)
+	if !f.failed {
+		f.failed = true
+		return errTestStart
+	}
+	return nil
+}
+
+func TestStartMissingAfterFailedStart(t *testing.T) {
+	m, _ := newTestManager()
+	rec := &CallRecorder{}
+	m.Add(NewNull("A", rec))
+	m.Add(&flakyStart{NullService: NewNull("B", rec)})
+	if err := m.StartAll(context.Background()); err == nil {
+		t.Fatal("StartAll returned nil, want B's failure")
+	}
+	if err := m.StartMissing(context.Background()); err != nil {
+		t.Fatalf("StartMissing: %v", err)
+	}
+
+	var names []string
+	for _, s := range m.Started() {
+		names = append(names, m.nameOf(s))
+	}
+	if !slices.Equal(names, []string{"A", "B"}) {
+		t.Errorf("Started() = %v, want [A B]", names)
+	}
+	m.StopAll(context.Background())
+	want := []string{"start A", "start B", "stop A", "start A", "start B", "stop B", "stop A"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+func TestStartMissingConcurrently(t *testing.T) {
+	m, _ := newTestManager()
+	rec := &CallRecorder{}
+	m.Add(NewNull("A", rec))
+	m.Add(NewNull("B", rec))
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.StartMissing(context.Background()); err != nil {
+				t.Errorf("StartMissing: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	m.StopAll(context.Background())
+
+	want := []string{"start A", "start B", "stop B", "stop A"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if err := m.StartMissing(context.Background()); err == nil {
+		t.Error("StartMissing after StopAll returned nil, want an error")
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// RunOption overrides a Manager setting for a single Run.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	startBudget time.Duration
+	stopBudget  time.Duration
+	grace       time.Duration
+}
+
+// WithStartBudget bounds the start phase by d instead of StartupTimeout.
+func WithStartBudget(d time.Duration) RunOption {
+	return func(c *runConfig) { c.startBudget = d }
+}
+
+// WithStopBudget bounds the stop phase by d instead of ShutdownTimeout.
+func WithStopBudget(d time.Duration) RunOption {
+	return func(c *runConfig) { c.stopBudget = d }
+}
+
+// WithGracePeriod waits d after a signal instead of ShutdownGracePeriod.
+func WithGracePeriod(d time.Duration) RunOption {
+	return func(c *runConfig) { c.grace = d }
+}
+
+// Run starts every service, waits until a signal arrives, ctx is cancelled
+// or Shutdown is called, and then stops everything in reverse. The returned
+// Result summarizes what happened to every service, even when a phase
+// failed, and the error joins the failures of every phase. A second signal
+// while services are being stopped exits the process immediately with
+// status 1.
+//
+// If ShutdownGracePeriod is set, Run waits that long after a signal before
+// stopping anything, leaving the services running meanwhile; a second signal
+// ends the wait early, and a third forces the exit.
+//
+// If MaxUptime is set, Run also shuts down on its own once the services have
+// been up that long; that counts as a clean shutdown. If HealthInterval is
+// set, a Critical service failing a health check shuts it down too, and the
+// failure is returned.
+//
+// opts override StartupTimeout, ShutdownTimeout and ShutdownGracePeriod for
+// this call only, leaving the Manager's fields untouched, so one setup can
+// be run again with different budgets. Each Run starts afresh: what the
+// previous one started, recorded or was asked to do, including a Shutdown
+// call made since, is forgotten, and Done returns a new channel. Once a Run
+// has returned, Events returns the channel the next one delivers on. Runs
+// must not overlap, and Run doesn't return before every Runnable's Run has.
+//
+// Run is meant to be embedded: ctx is the parent of every context handed to
+// services. Cancelling it, a signal or a call to Shutdown aborts a startup in
+// progress before the next service is started, or begins shutdown during the
+// working phase; whichever of ctx and a signal fires first wins.
+// The stop phase keeps ctx's values but not its cancellation, so cancelling
+// ctx to request shutdown still leaves services their stop budget.
+func (m *Manager) Run(ctx context.Context, opts ...RunOption) (Result, error) {
+	cfg := runConfig{
+		startBudget: m.StartupTimeout,
+		stopBudget:  m.ShutdownTimeout,
+		grace:       m.ShutdownGracePeriod,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	m.lastRunMu.Lock()
+	again := m.lastRunEnded
+	m.lastRunMu.Unlock()
+	if again {
+		m.resetRun()
+	}
+	m.setLastRun(false, nil)
+	done := m.Done()
+	// wg counts the goroutines Run starts, which must all be gone before it
+	// returns, so none of them can act on a later Run.
+	var wg sync.WaitGroup
+
+	sig := m.Signals()
+	defer m.StopSignals()
+
+	force, forceNow := context.WithCancel(context.WithoutCancel(ctx))
+	defer forceNow()
+	// startCtx is also cancelled once shutdown begins, so a signal during
+	// startup stops further services from being started.
+	startCtx, cancelStart := context.WithCancel(ctx)
+	defer cancelStart()
+	if cfg.startBudget > 0 {
+		startCtx, cancelStart = withTimeout(startCtx, m.Clock, cfg.startBudget)
+		defer cancelStart()
+	}
+	stopped := make(chan struct{})
+	inGrace, skipGrace, graceOver := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	forceExit := func() {
+		m.Logger.Warnf("second signal received, forcing exit")
+		forceNow()
+		os.Exit(1)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case s := <-sig:
+			m.Logger.Infof("received signal %v, shutting down", s)
+			m.shutdownOnSignal(s)
+		case <-ctx.Done():
+			m.Shutdown()
+		case <-done:
+		}
+		cancelStart()
+		// A second signal during the grace period only cuts it short; at
+		// any other time, such as during a rollback, it forces the exit.
+		select {
+		case <-inGrace:
+			select {
+			case <-sig:
+				m.Logger.Warnf("second signal received, skipping grace period")
+				close(skipGrace)
+			case <-graceOver:
+			}
+		case <-sig:
+			forceExit()
+		case <-graceOver:
+		}
+		// A signal while stopping means the operator gave up waiting.
+		select {
+		case <-sig:
+			forceExit()
+		case <-stopped:
+		}
+	}()
+
+	var errs []error
+	startErr := m.StartAll(startCtx)
+	if err := startErr; err != nil {
+		m.Logger.Errorf("%v", err)
+		errs = append(errs, err)
+		m.shutdown(ReasonStartFailed)
+	} else if m.ExitWhenEmpty && len(m.registered()) == 0 {
+		m.Shutdown()
+	} else {
+		m.superviseAll(ctx, &wg)
+		if m.MaxUptime > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				m.expireAfter(m.MaxUptime, done)
+			}()
+		}
+		if m.HealthInterval > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				m.watchHealth(ctx)
+			}()
+		}
+	}
+
+	<-done
+	if err := m.RuntimeErr(); err != nil {
+		errs = append(errs, err)
+	}
+	if startErr == nil && m.shutdownReason() == ReasonSignal && cfg.grace > 0 {
+		m.Logger.Infof("grace period (%v) before shutdown", cfg.grace)
+		close(inGrace)
+		select {
+		case <-m.Clock.After(cfg.grace):
+		case <-skipGrace:
+		}
+	}
+	close(graceOver)
+
+	stopCtx, cancel := withTimeout(force, m.Clock, cfg.stopBudget)
+	err := m.StopAll(stopCtx)
+	cancel()
+	close(stopped)
+	wg.Wait()
+	if err != nil && !m.IgnoreStopErrors {
+		errs = append(errs, err)
+	}
+	if m.ResultPath != "" {
+		if err := m.WriteResult(m.ResultPath); err != nil {
+			m.Logger.Errorf("%v", err)
+		}
+	}
+	err = errors.Join(errs...)
+	m.setLastRun(true, err)
+	return m.Result(), err
+}
+
+// LastRunClean reports whether the last Run has returned and returned nil.
+// It is false while a Run is in progress.
+func (m *Manager) LastRunClean() bool {
+	m.lastRunMu.Lock()
+	defer m.lastRunMu.Unlock()
+	return m.lastRunEnded && m.lastRunErr == nil
+}
+
+// LastRunError returns the error the last Run returned, or nil if it
+// returned nil or hasn't returned yet.
+func (m *Manager) LastRunError() error {
+	m.lastRunMu.Lock()
+	defer m.lastRunMu.Unlock()
+	return m.lastRunErr
+}
+
+// setLastRun records the outcome LastRunClean and LastRunError report.
+func (m *Manager) setLastRun(ended bool, err error) {
+	m.lastRunMu.Lock()
+	m.lastRunEnded, m.lastRunErr = ended, err
+	m.lastRunMu.Unlock()
+}
+
+// resetRun forgets what the previous Run left behind, so the Manager can be
+// run again: the shutdown and its reason, the stop phase, the events
+// channel, unless Events has already replaced it, what was started and the
+// failures recorded along the way.
+func (m *Manager) resetRun() {
+	m.shutdownMu.Lock()
+	m.done, m.signal = make(chan struct{}), nil
+	m.shutdownMu.Unlock()
+	m.stopOnce, m.stopErr = sync.Once{}, nil
+
+	m.eventsMu.Lock()
+	if m.eventsClosed {
+		m.events, m.eventsClosed = make(chan Event, eventBuffer), false
+	}
+	m.eventsMu.Unlock()
+
+	m.changeMu.Lock()
+	m.stopping = false
+	m.changeMu.Unlock()
+	m.setStarted(nil)
+
+	m.runtimeMu.Lock()
+	m.runtimeErr = nil
+	m.runtimeMu.Unlock()
+	m.stopTimeoutsMu.Lock()
+	m.stopTimeouts = nil
+	m.stopTimeoutsMu.Unlock()
+}
+
+// expireAfter shuts down after d unless done is closed first.
+func (m *Manager) expireAfter(d time.Duration, done <-chan struct{}) {
+	select {
+	case <-m.Clock.After(d):
+		m.Logger.Infof("max uptime reached, shutting down")
+		m.shutdown(ReasonMaxUptime)
+	case <-done:
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunWithNoServices(t *testing.T) {
+	m, logger := newTestManager()
+	m.ExitWhenEmpty = true
+	_, err := m.Run(context.Background())
+	if code := ExitCodeOf(err); code != ExitClean {
+		t.Errorf("ExitCodeOf(%v) = %d, want %d", err, code, ExitClean)
+	}
+	if !logger.Contains("[INFO] no services to start") {
+		t.Errorf("empty start not logged; got %q", logger.Lines())
+	}
+}
+
+func TestRunWithNoServicesWaitsForShutdown(t *testing.T) {
+	m, _ := newTestManager()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := m.Run(ctx)
+	if code := ExitCodeOf(err); code != ExitClean {
+		t.Errorf("ExitCodeOf(%v) = %d, want %d", err, code, ExitClean)
+	}
+	if ctx.Err() == nil {
+		t.Error("Run returned before its context was done")
+	}
+}
+
+func TestSignalDuringStartupSkipsRemainingServices(t *testing.T) {
+	m, _ := newTestManager()
+	logger := &testLogger{}
+	a := New("A", WithFakeDuration(0), WithLogger(logger))
+	b := New("B", WithFakeDuration(200*time.Millisecond), WithLogger(logger))
+	c := New("C", WithFakeDuration(0), WithLogger(logger))
+	m.Add(a)
+	m.Add(b)
+	m.Add(c)
+	m.NotifyOn(syscall.SIGUSR1)
+	m.Signals()
+	go func() {
+		waitFor(func() bool { return b.State() == StateStarting })
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	}()
+
+	_, err := m.Run(context.Background())
+	if err == nil {
+		t.Error("Run returned nil after startup was aborted")
+	}
+	if logger.Contains("starting service C") {
+		t.Error("C was started after the signal")
+	}
+	if a.IsRunning() {
+		t.Error("A was left running")
+	}
+}
+
+// forceExitEnv makes the test binary run forceExitDuringRollback instead of
+// the tests, since os.Exit can only be observed from another process.
+const forceExitEnv = "LIFECYCLE_TEST_FORCE_EXIT"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(forceExitEnv) != "" {
+		forceExitDuringRollback()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// forceExitDuringRollback signals itself once while B is starting and once
+// more while A is being rolled back, with a grace period configured.
+func forceExitDuringRollback() {
+	m, _ := newTestManager()
+	m.ShutdownGracePeriod = time.Minute
+	a := New("A", WithFakeDuration(0), WithStopDelay(10*time.Second), WithStopTimeout(20*time.Second))
+	b := New("B", WithFakeDuration(10*time.Second), WithStartTimeout(20*time.Second))
+	m.Add(a)
+	m.Add(b)
+	m.NotifyOn(syscall.SIGUSR1)
+	m.Signals()
+	go func() {
+		waitFor(func() bool { return b.State() == StateStarting })
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+		waitFor(func() bool { return a.State() == StateStopping })
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	}()
+	m.Run(context.Background())
+}
+
+func TestSecondSignalDuringRollbackForcesExit(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), forceExitEnv+"=1")
+	began := time.Now()
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != 1 {
+		t.Fatalf("child exited with %v, want status 1", err)
+	}
+	if took := time.Since(began); took > 5*time.Second {
+		t.Errorf("child took %v to exit, so the rollback wasn't cut short", took)
+	}
+}
+
+var errTestStart = errors.New("start failed")
+
+// failingStart records its Start through the embedded NullService and then
+// fails.
+type failingStart struct {
+	*NullService
+}
+
+func (f failingStart) Start(ctx context.Context) error {
+	f.NullService.Start(ctx)
+	return errTestStart
+}
+
+func TestRunOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		failB bool
+		want  []string
+	}{
+		{
+			name: "all start",
+			want: []string{"start A", "start B", "start C", "stop C", "stop B", "stop A"},
+		},
+		{
+			name:  "B fails to start",
+			failB: true,
+			want:  []string{"start A", "start B", "stop A"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			rec := &CallRecorder{}
+			m.Add(NewNull("A", rec))
+			if tt.failB {
+				m.Add(failingStart{NewNull("B", rec)})
+			} else {
+				m.Add(NewNull("B", rec))
+			}
+			m.Add(NewNull("C", rec))
+			m.NotifyOn(syscall.SIGUSR1)
+			m.Signals()
+			if !tt.failB {
+				go func() {
+					waitFor(func() bool { return len(rec.Calls()) == 3 })
+					syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+				}()
+			}
+
+			_, err := m.Run(context.Background())
+			if got := err != nil; got != tt.failB {
+				t.Errorf("Run error = %v", err)
+			}
+			if got := rec.Calls(); !slices.Equal(got, tt.want) {
+				t.Errorf("calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTwice(t *testing.T) {
+	m, _ := newTestManager()
+	m.MaxUptime = 20 * time.Millisecond
+	rec := &CallRecorder{}
+	m.Add(NewNull("A", rec))
+
+	for run := 1; run <= 2; run++ {
+		events := m.Events()
+		var got []string
+		collected := make(chan struct{})
+		go func() {
+			defer close(collected)
+			for e := range events {
+				got = append(got, e.ServiceName+" "+e.State.String())
+			}
+		}()
+		if _, err := m.Run(context.Background()); err != nil {
+			t.Fatalf("Run %d: %v", run, err)
+		}
+		<-collected
+		if len(got) == 0 {
+			t.Errorf("Run %d delivered no events", run)
+		}
+		if !m.LastRunClean() {
+			t.Errorf("Run %d wasn't clean", run)
+		}
+	}
+	want := []string{"start A", "stop A", "start A", "stop A"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+func TestRunContinuesPastStopTimeout(t *testing.T) {
+	m, _ := newTestManager()
+	logger := &testLogger{}
+	a := New("A", WithFakeDuration(0), WithLogger(logger))
+	b := New("B", WithFakeDuration(0), WithStopDelay(time.Second), WithStopTimeout(20*time.Millisecond), WithLogger(logger))
+	c := New("C", WithFakeDuration(0), WithLogger(logger))
+	m.Add(a)
+	m.Add(b)
+	m.Add(c)
+	m.NotifyOn(syscall.SIGUSR1)
+	m.Signals()
+	go func() {
+		waitFor(c.IsRunning)
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	}()
+
+	_, err := m.Run(context.Background())
+	if !errors.Is(err, ErrStopTimeout) {
+		t.Errorf("Run error = %v, want B's stop timeout", err)
+	}
+	if code := ExitCodeOf(err); code != ExitStopFailure {
+		t.Errorf("ExitCodeOf = %d, want %d", code, ExitStopFailure)
+	}
+	for _, name := range []string{"A", "B", "C"} {
+		if !logger.Contains("stopping service " + name) {
+			t.Errorf("Stop of %s not attempted", name)
+		}
+	}
+	if a.State() != StateStopped || c.State() != StateStopped {
+		t.Errorf("A is %v and C is %v, want both stopped", a.State(), c.State())
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// canChange returns an error if shutdown has begun, in which case the
+// runtime change called what may no longer be made. m.changeMu must be held.
+func (m *Manager) canChange(what string) error {
+	select {
+	case <-m.Done():
+		return fmt.Errorf("can't %s during shutdown", what)
+	default:
+	}
+	if m.stopping {
+		return fmt.Errorf("can't %s during shutdown", what)
+	}
+	return nil
+}
+
+// AddAndStart registers s and starts it straight away, during the working
+// phase. It joins the end of the start order, so shutdown stops it first.
+// If s fails to start it is unregistered again. It fails once shutdown has
+// begun.
+func (m *Manager) AddAndStart(ctx context.Context, s Service) error {
+	m.changeMu.Lock()
+	defer m.changeMu.Unlock()
+	if err := m.canChange("add a service"); err != nil {
+		return err
+	}
+	if err := m.Add(s); err != nil {
+		return err
+	}
+
+	ctx = m.withRun(ctx)
+	err := m.start(ctx, s)
+	if err == nil {
+		if err = m.checkHealth(ctx, []Service{s}); err != nil {
+			m.unwindOne(ctx, s)
+		}
+	}
+	if err != nil {
+		m.unregister(s)
+		return err
+	}
+	m.addStarted([]Service{s})
+	return nil
+}
+
+// RemoveAndStop unregisters the service called name and stops it if it is
+// up. The service is removed even if its Stop fails; the stop error is
+// returned. It fails once shutdown has begun.
+func (m *Manager) RemoveAndStop(ctx context.Context, name string) error {
+	m.changeMu.Lock()
+	defer m.changeMu.Unlock()
+	if err := m.canChange("remove a service"); err != nil {
+		return err
+	}
+	s, ok := m.Get(name)
+	if !ok {
+		return fmt.Errorf("no service named %s", name)
+	}
+
+	var err error
+	if m.isUp(s) {
+		err = m.stop(m.withRun(ctx), s)
+	}
+	m.unregister(s)
+	return err
+}
+
+// unregister removes s from the registration and from the started services.
+func (m *Manager) unregister(s Service) {
+	m.regMu.Lock()
+	m.services = slices.DeleteFunc(m.services, func(other Service) bool { return other == s })
+	m.stages = removeFromStages(m.stages, s)
+	if n, ok := s.(Named); ok {
+		delete(m.byName, n.Name())
+		delete(m.namedIDs, n.Name())
+		delete(m.deps, n.Name())
+		delete(m.configs, n.Name())
+	} else {
+		delete(m.unnamedIDs, s)
+	}
+	m.regMu.Unlock()
+
+	m.startedMu.Lock()
+	m.started = removeFromStages(m.started, s)
+	m.startedMu.Unlock()
+}
+
+// removeFromStages returns stages without s, dropping stages left empty.
+func removeFromStages(stages [][]Service, s Service) [][]Service {
+	var out [][]Service
+	for _, stage := range stages {
+		stage = slices.DeleteFunc(slices.Clone(stage), func(other Service) bool { return other == s })
+		if len(stage) > 0 {
+			out = append(out, stage)
+		}
+	}
+	return out
+}
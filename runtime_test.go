@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// anonymous is a service without a Name that records its calls as label.
+type anonymous struct {
+	label string
+	rec   *CallRecorder
+}
+
+func (a *anonymous) Start(context.Context) error {
+	a.rec.record("start " + a.label)
+	return nil
+}
+
+func (a *anonymous) Stop(context.Context) error {
+	a.rec.record("stop " + a.label)
+	return nil
+}
+
+func TestAddRejectsDuplicateNames(t *testing.T) {
+	m, _ := newTestManager()
+	if err := m.Add(NewNull("A", &CallRecorder{})); err != nil {
+		t.Fatalf("first Add: %v", err)
+	}
+	err := m.Add(NewNull("A", &CallRecorder{}))
+	if err == nil || !strings.Contains(err.Error(), "duplicate service name A: service#0 and service#1") {
+		t.Errorf("second Add error = %v, want one naming both registrations", err)
+	}
+	if n := len(m.registered()); n != 1 {
+		t.Errorf("%d services registered, want 1", n)
+	}
+}
+
+func TestUnnamedServiceKeepsNameAfterRemoval(t *testing.T) {
+	m, _ := newTestManager()
+	rec := &CallRecorder{}
+	m.Add(NewNull("N", rec))
+	u := &anonymous{label: "U", rec: rec}
+	m.Add(u)
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+	before := m.nameOf(u)
+	if err := m.RemoveAndStop(context.Background(), "N"); err != nil {
+		t.Fatalf("RemoveAndStop: %v", err)
+	}
+	if after := m.nameOf(u); after != before {
+		t.Errorf("name changed from %s to %s", before, after)
+	}
+	m.StopAll(context.Background())
+
+	want := []string{"start N", "start U", "stop N", "stop U"}
+	if got := rec.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+// withHook is a named service that can't be compared, because of its func
+// field.
+type withHook struct {
+	*NullService
+	hook func()
+}
+
+// unnamedHook is the same without a Name.
+type unnamedHook struct {
+	hook func()
+}
+
+func (unnamedHook) Start(context.Context) error { return nil }
+func (unnamedHook) Stop(context.Context) error  { return nil }
+
+func TestAddRejectsUncomparableServices(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Service
+	}{
+		{"named", withHook{NewNull("H", &CallRecorder{}), func() {}}},
+		{"unnamed", unnamedHook{func() {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			rec := &CallRecorder{}
+			m.Add(NewNull("A", rec))
+			if err := m.StartAll(context.Background()); err != nil {
+				t.Fatalf("StartAll: %v", err)
+			}
+			if err := m.Add(tt.s); err == nil || !strings.Contains(err.Error(), "not comparable") {
+				t.Errorf("Add error = %v, want one saying the service is not comparable", err)
+			}
+			if err := m.AddAndStart(context.Background(), tt.s); err == nil {
+				t.Error("AddAndStart succeeded, want an error")
+			}
+			if err := m.RemoveAndStop(context.Background(), "H"); err == nil {
+				t.Error("RemoveAndStop of a refused service succeeded, want an error")
+			}
+			if err := m.RemoveAndStop(context.Background(), "A"); err != nil {
+				t.Errorf("RemoveAndStop(A): %v", err)
+			}
+			if n := len(m.registered()); n != 0 {
+				t.Errorf("%d services registered, want 0", n)
+			}
+			m.StopAll(context.Background())
+		})
+	}
+}
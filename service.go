@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Service is anything that can be brought up and torn down under a context.
+type Service interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Named is implemented by services that have a name for logs and errors.
+type Named interface {
+	Name() string
+}
+
+// Timeouts is implemented by services that declare their own start and stop
+// budgets. A zero duration means the package default.
+type Timeouts interface {
+	StartTimeout() time.Duration
+	StopTimeout() time.Duration
+}
+
+// HealthChecker is implemented by services that can report whether they are
+// actually serving after Start returned. Services that don't implement it
+// are considered healthy.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
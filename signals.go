@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NotifyOn sets the signals that trigger graceful shutdown. It replaces the
+// default of SIGINT and SIGTERM and must be called before Signals.
+func (m *Manager) NotifyOn(signals ...os.Signal) {
+	m.signals = signals
+}
+
+// Signals registers the configured signals and returns the channel they are
+// delivered on. Call StopSignals once shutdown is over to release the
+// handler.
+func (m *Manager) Signals() <-chan os.Signal {
+	if m.sigCh == nil {
+		signals := m.signals
+		if len(signals) == 0 {
+			signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+		}
+		m.sigCh = make(chan os.Signal, 1)
+		signal.Notify(m.sigCh, signals...)
+	}
+	return m.sigCh
+}
+
+// StopSignals undoes the registration made by Signals.
+func (m *Manager) StopSignals() {
+	if m.sigCh != nil {
+		signal.Stop(m.sigCh)
+		m.sigCh = nil
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+// State is where a service is in its lifecycle.
+type State int
+
+const (
+	StateStopped State = iota
+	StateStarting
+	StateRunning
+	StateStopping
+	StateFailed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateStopped:
+		return "stopped"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateStopping:
+		return "stopping"
+	case StateFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// Stateful is implemented by services that report their lifecycle state.
+type Stateful interface {
+	State() State
+}
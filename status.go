@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ServeStatus registers an HTTP server on addr exposing /status, a JSON list
+// of every service's name and state, and /metrics, the output of
+// WritePrometheus. A service that isn't Stateful is reported running or
+// stopped, by the same rule StartMissing uses. The server is itself a
+// service placed ahead of everything else, so it comes up first and goes
+// down last. It fails if a service named "status" is already registered.
+func (m *Manager) ServeStatus(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", m.handleStatus)
+	mux.HandleFunc("/metrics", m.handleMetrics)
+
+	svc := NewHTTPServerService("status", func() *http.Server {
+		return &http.Server{Addr: addr, Handler: mux}
+	})
+	m.regMu.Lock()
+	defer m.regMu.Unlock()
+	if err := m.indexLocked([]Service{svc}); err != nil {
+		return err
+	}
+	m.services = append([]Service{svc}, m.services...)
+	m.stages = append([][]Service{{svc}}, m.stages...)
+	return nil
+}
+
+type serviceStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+func (m *Manager) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	services := m.registered()
+	statuses := make([]serviceStatus, 0, len(services))
+	for _, s := range services {
+		state := StateStopped
+		if sf, ok := s.(Stateful); ok {
+			state = sf.State()
+		} else if m.isUp(s) {
+			state = StateRunning
+		}
+		statuses = append(statuses, serviceStatus{Name: m.nameOf(s), State: state.String()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+func (m *Manager) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WritePrometheus(w)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestStatusReportsServicesWithoutState(t *testing.T) {
+	m, _ := newTestManager()
+	rec := &CallRecorder{}
+	m.Add(NewNull("A", rec))
+	m.Add(&anonymous{label: "U", rec: rec})
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+	if err := m.RemoveAndStop(context.Background(), "A"); err != nil {
+		t.Fatalf("RemoveAndStop: %v", err)
+	}
+	m.Add(NewNull("B", rec))
+
+	w := httptest.NewRecorder()
+	m.handleStatus(w, httptest.NewRequest("GET", "/status", nil))
+	var got []serviceStatus
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding status: %v", err)
+	}
+	want := []serviceStatus{{m.nameOf(m.registered()[0]), "running"}, {"B", "stopped"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("status = %v, want %v", got, want)
+	}
+	m.StopAll(context.Background())
+}
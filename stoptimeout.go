@@ -0,0 +1,36 @@
+package main
+
+import "time"
+
+// StopTimeoutPolicy chooses how long each Stop may take. The zero value
+// keeps the fixed timeouts: the service's own StopTimeout, or
+// DefaultStopTimeout.
+type StopTimeoutPolicy struct {
+	// StartFactor, if positive, makes a service's stop timeout that
+	// multiple of how long its last Start took, as recorded in Metrics. A
+	// service with no recorded start falls back to the fixed timeout.
+	StartFactor float64
+	// Min and Max clamp a timeout derived from StartFactor. Either can be
+	// zero to leave that side unclamped. They don't apply to fixed timeouts.
+	Min, Max time.Duration
+}
+
+// stopTimeout returns the budget for one Stop of s under m.StopTimeoutPolicy.
+func (m *Manager) stopTimeout(s Service) time.Duration {
+	p := m.StopTimeoutPolicy
+	if p.StartFactor <= 0 {
+		return stopTimeoutFor(s)
+	}
+	started, ok := m.Metrics().StartDurations[m.nameOf(s)]
+	if !ok {
+		return stopTimeoutFor(s)
+	}
+	d := time.Duration(float64(started) * p.StartFactor)
+	if p.Min > 0 && d < p.Min {
+		d = p.Min
+	}
+	if p.Max > 0 && d > p.Max {
+		d = p.Max
+	}
+	return d
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStopTimeoutIsTalliedWithoutLeak(t *testing.T) {
+	m, logger := newTestManager()
+	m.Add(New("A", WithFakeDuration(0)))
+	m.Add(New("B", WithFakeDuration(0), WithStopDelay(5*time.Second), WithStopTimeout(10*time.Millisecond)))
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+
+	before := runtime.NumGoroutine()
+	err := m.StopAll(context.Background())
+	if err == nil {
+		t.Fatal("StopAll returned nil, want B's stop timeout")
+	}
+	if got := m.StopTimeouts(); !slices.Equal(got, []string{"B"}) {
+		t.Errorf("StopTimeouts() = %v, want [B]", got)
+	}
+	if !logger.Contains("1 service(s) exceeded their stop deadline: B") {
+		t.Errorf("stop deadline tally not logged; got %q", logger.Lines())
+	}
+	if n := settleGoroutines(before); n > before {
+		t.Errorf("goroutines: %d before StopAll, %d after", before, n)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Runnable is implemented by services that do their work in a long-running
+// Run call after Start. Run should return when ctx is cancelled; a non-nil
+// error before that counts as a crash.
+type Runnable interface {
+	Run(ctx context.Context) error
+}
+
+// Supervise runs every started Runnable service in its own goroutine for as long as
+// the program is in its working phase. A crashed service is restarted up to
+// MaxRestarts times; after that the failure is reported by RuntimeErr and
+// shutdown begins. Restarts stop as soon as shutdown begins.
+func (m *Manager) Supervise(ctx context.Context) {
+	m.superviseAll(ctx, new(sync.WaitGroup))
+}
+
+// superviseAll is Supervise, counting the goroutines it starts in wg so Run
+// can wait for them.
+func (m *Manager) superviseAll(ctx context.Context, wg *sync.WaitGroup) {
+	ctx, cancel := context.WithCancel(m.withRun(ctx))
+	done := m.Done()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	for _, s := range m.Started() {
+		if r, ok := s.(Runnable); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				m.supervise(ctx, s, r)
+			}()
+		}
+	}
+}
+
+func (m *Manager) supervise(ctx context.Context, s Service, r Runnable) {
+	for restarts := 0; ; restarts++ {
+		err := r.Run(ctx)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if restarts >= m.MaxRestarts {
+			m.Logger.Errorf("service %s crashed: %v, giving up after %d restarts", m.nameOf(s), err, restarts)
+			m.fail(fmt.Errorf("service %s crashed: %w", m.nameOf(s), err))
+			return
+		}
+		m.Logger.Warnf("service %s crashed: %v, restarting (%d/%d)", m.nameOf(s), err, restarts+1, m.MaxRestarts)
+	}
+}
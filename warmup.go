@@ -0,0 +1,39 @@
+package main
+
+import "context"
+
+// Warmer is implemented by services that need to prepare, such as priming
+// caches, after Start returns and before they count as up. Warmup runs
+// before any HealthCheck, under the service's start timeout.
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// warmup runs s's Warmup, if it has one. A failed warmup is a failed start:
+// s is stopped again straight away and the warmup error is returned, so the
+// rest of the startup unwinds as it would for a failing Start.
+func (m *Manager) warmup(ctx context.Context, s Service) error {
+	w, ok := s.(Warmer)
+	if !ok {
+		return nil
+	}
+	warmCtx, cancel := withTimeout(ctx, m.Clock, startTimeoutFor(s))
+	err := m.guard(s, PhaseWarmup, func() error { return w.Warmup(warmCtx) })
+	cancel()
+	if err == nil {
+		return nil
+	}
+
+	m.unwindOne(ctx, s)
+	return err
+}
+
+// unwindOne stops s, whose Start succeeded but which failed to finish
+// coming up, as part of unwinding the startup.
+func (m *Manager) unwindOne(ctx context.Context, s Service) {
+	stopCtx, cancel := m.rollbackCtx(ctx)
+	defer cancel()
+	if err := m.stop(stopCtx, s); err != nil {
+		m.logStopErr(err)
+	}
+}